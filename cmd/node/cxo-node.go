@@ -1,18 +1,177 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/logging"
 	"github.com/SkycoinProject/cxo-2/pkg/node"
 	"github.com/SkycoinProject/cxo-2/pkg/node/data"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	migrateCmd        = "migrate"
+	migrateObjectsCmd = "migrate-objects"
+	fsckCmd           = "fsck"
 )
 
 func main() {
 	local := flag.Bool("local", false, "enables node to run from the path it's been started from")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [%s [--dry-run] | %s | %s [--repair]]\n", os.Args[0], migrateCmd, migrateObjectsCmd, fsckCmd)
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s\n\tapply pending database migrations and exit, --dry-run only lists them\n", migrateCmd)
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s\n\tmove objects stored in database to objects directory and exit\n", migrateObjectsCmd)
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s\n\tcheck integrity of stored data and exit, --repair removes orphans and re-fetches missing data\n", fsckCmd)
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 	cfg := config.LoadConfig(local)
-	tearDown := data.Init()
-	defer tearDown()
-	node.NewService(cfg).Run()
+	// node logs to the standard logger, so entries of dependencies using it are formatted the same way
+	logger := log.StandardLogger()
+	logging.Configure(logger, cfg.Log)
+	gin.SetMode(gin.ReleaseMode)
+
+	switch flag.Arg(0) {
+	case "":
+	case migrateCmd:
+		migrateFlags := flag.NewFlagSet(migrateCmd, flag.ExitOnError)
+		dryRun := migrateFlags.Bool("dry-run", false, "list pending migrations without applying them")
+		_ = migrateFlags.Parse(flag.Args()[1:])
+		migrateDatabase(cfg.Storage, *dryRun)
+		return
+	case migrateObjectsCmd:
+		migrateObjects(cfg.Storage)
+		return
+	case fsckCmd:
+		fsckFlags := flag.NewFlagSet(fsckCmd, flag.ExitOnError)
+		repair := fsckFlags.Bool("repair", false, "remove orphaned and corrupted data and re-fetch incomplete feeds")
+		_ = fsckFlags.Parse(flag.Args()[1:])
+		fsck(cfg, *repair)
+		return
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, tearDown, err := data.Open(cfg.Storage, logger)
+	if err != nil {
+		log.Fatal("Opening data store failed due to error: ", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Infof("Received signal: %v, shutting down", sig)
+		cancel()
+		// second signal stops the node right away
+		<-signals
+		os.Exit(1)
+	}()
+
+	err = node.NewService(cfg, db, logger).Run(ctx)
+	tearDown()
+	if err != nil {
+		log.Fatal("Running node failed due to error: ", err)
+	}
+}
+
+func migrateDatabase(cfg config.StorageConfig, dryRun bool) {
+	if cfg.Backend == config.MemoryStorage {
+		log.Fatalf("Nothing to migrate with %v storage backend", cfg.Backend)
+	}
+
+	pending, err := data.PendingMigrations(cfg.DatabasePath, log.StandardLogger())
+	if err != nil {
+		log.Fatal("Checking pending migrations failed due to error: ", err)
+	}
+	if len(pending) == 0 {
+		log.Info("Database schema is up to date")
+		return
+	}
+	for _, m := range pending {
+		log.Info("Pending migration ", m)
+	}
+	if dryRun {
+		return
+	}
+
+	if err := data.Migrate(cfg.DatabasePath, log.StandardLogger()); err != nil {
+		log.Fatal("Migrating database failed due to error: ", err)
+	}
+	log.Info("Migration finished successfully")
+}
+
+func migrateObjects(cfg config.StorageConfig) {
+	if cfg.Backend != config.FilesystemStorage {
+		log.Fatalf("Objects can be migrated only with %v storage backend, current backend is %v", config.FilesystemStorage, cfg.Backend)
+	}
+
+	moved, err := data.MigrateObjects(cfg.DatabasePath, cfg.ObjectsPath, log.StandardLogger())
+	if err != nil {
+		log.Fatalf("Migrating objects failed after moving %v objects due to error: %v", moved, err)
+	}
+	log.Infof("Migration finished successfully, %v objects moved to: %v", moved, cfg.ObjectsPath)
+}
+
+func fsck(cfg config.Config, repair bool) {
+	if cfg.Storage.Backend == config.MemoryStorage {
+		log.Fatalf("Nothing to check with %v storage backend", cfg.Storage.Backend)
+	}
+
+	logger := log.StandardLogger()
+	db, tearDown, err := data.Open(cfg.Storage, logger)
+	if err != nil {
+		log.Fatal("Opening data store failed due to error: ", err)
+	}
+
+	report, err := node.NewService(cfg, db, logger).Fsck(repair)
+	// data store is closed before exiting, since exit skips deferred calls
+	tearDown()
+	if err != nil {
+		log.Fatal("Checking data failed due to error: ", err)
+	}
+
+	log.Infof("Checked %v feeds, %v object headers and %v objects", report.Feeds, report.ObjectHeaders, report.Objects)
+	for _, problem := range report.DanglingReferences {
+		log.Warn("Dangling reference: ", problem)
+	}
+	for _, hash := range report.CorruptedHeaders {
+		log.Warn("Corrupted object header: ", hash)
+	}
+	for _, hash := range report.CorruptedObjects {
+		log.Warn("Corrupted object: ", hash)
+	}
+	for _, hash := range report.OrphanedHeaders {
+		log.Warn("Orphaned object header: ", hash)
+	}
+	for _, hash := range report.OrphanedObjects {
+		log.Warn("Orphaned object: ", hash)
+	}
+	for _, key := range report.IncompleteRootHashes {
+		log.Warn("Incomplete root hash: ", key)
+	}
+
+	if !report.HasProblems() {
+		log.Info("No problems found")
+		return
+	}
+	// non-zero exit status lets scripts detect problems that are left
+	if !repair {
+		log.Infof("Run with %v --repair to fix found problems", fsckCmd)
+		os.Exit(1)
+	}
+	log.Infof("Removed %v object headers and %v objects, re-fetched %v of %v incomplete root hashes",
+		len(report.RemovedHeaders), len(report.RemovedObjects), len(report.RepairedRootHashes), len(report.IncompleteRootHashes))
+	if len(report.RepairedRootHashes) < len(report.IncompleteRootHashes) {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/SkycoinProject/cxo-2/pkg/tracker/fake"
+	"github.com/SkycoinProject/cxo-2/pkg/transport"
+	dmsghttp "github.com/SkycoinProject/dmsg-http"
+	"github.com/SkycoinProject/dmsg/cipher"
+	"github.com/SkycoinProject/dmsg/disc"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	address := flag.String("address", "127.0.0.1:8084", "address tracker listens on")
+	discovery := flag.String("discovery", dmsghttp.DefaultDiscoveryURL, "dmsg discovery used to notify nodes served over dmsg")
+	flag.Parse()
+
+	// tracker is served over plain HTTP only, the key pair is used just to reach nodes served over dmsg
+	pubKey, secKey := cipher.GenerateKeyPair()
+	tracker := fake.New(transport.NewClient(disc.NewHTTP(*discovery), pubKey, secKey))
+
+	log.Infof("Starting in-memory tracker, use http://%v as tracker URL of nodes", *address)
+	if err := http.ListenAndServe(*address, tracker); err != nil {
+		log.Fatal("Serving tracker failed due to error: ", err)
+	}
+}
@@ -12,7 +12,7 @@ import (
 	"time"
 
 	"github.com/SkycoinProject/cxo-2/pkg/model"
-	"github.com/SkycoinProject/skycoin/src/cipher"
+	"github.com/SkycoinProject/cxo-2/pkg/parcel"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -109,197 +109,61 @@ func publishDataCmd() *cobra.Command {
 }
 
 func prepareRequest(filePath string) (model.PublishDataRequest, error) {
-	parcel := model.Parcel{}
-
 	// we're supporting only one path in the request at a time
-	processPath(&parcel, filePath, []int{})
-
-	rootHash, err := constructRootHash(parcel)
+	entry, err := pathEntry(filePath)
 	if err != nil {
 		return model.PublishDataRequest{}, err
 	}
 
+	p, rootHeaderHash, err := parcel.Build(entry)
+	if err != nil {
+		return model.PublishDataRequest{}, fmt.Errorf("building parcel failed due to error: %v", err)
+	}
+
 	return model.PublishDataRequest{
-		RootHash: rootHash,
-		Parcel:   parcel,
+		RootHash: model.RootHash{
+			Timestamp:        time.Now(),
+			ObjectHeaderHash: rootHeaderHash,
+		},
+		Parcel: p,
 	}, nil
-
 }
 
-func processPath(parcel *model.Parcel, path string, parentDirectories []int) {
+// pathEntry - file becomes entry with its content as object, directory becomes entry referencing its content
+func pathEntry(path string) (parcel.Entry, error) {
 	isDir, err := isDirectory(path)
 	if err != nil {
-		fmt.Printf("Unable to parse path %s due to error %v", path, err)
-		return
-	}
-	if isDir {
-		headerIndex, subPaths := processDirectory(parcel, path, parentDirectories)
-		for _, subPath := range subPaths {
-			newParentDirectories := []int{}
-			newParentDirectories = append(newParentDirectories, parentDirectories...)
-			newParentDirectories = append(newParentDirectories, headerIndex)
-			processPath(parcel, subPath, newParentDirectories)
-		}
-		if len(parentDirectories) > 0 {
-			hash, err := sha256(parcel.ObjectHeaders[headerIndex])
-			if err != nil {
-				fmt.Printf("can't construct header hash for directory %s due to error %v", path, err)
-			} else {
-				constructExternalReference(parcel, hash, uint64(0), parentDirectories)
-			}
-		}
-	} else {
-		processFile(parcel, path, parentDirectories)
+		return parcel.Entry{}, fmt.Errorf("unable to parse path %s due to error %v", path, err)
 	}
-}
-
-func processDirectory(parcel *model.Parcel, path string, parentDirectories []int) (int, []string) {
-	dirName := filepath.Base(path)
-	dirIndex := len(parcel.ObjectHeaders)
 
-	objectHeader, err := constructDirHeader(dirName)
-	if err != nil {
-		processError("Error constructing object header", err)
+	if !isDir {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return parcel.Entry{}, fmt.Errorf("reading file: %v failed with error: %v", path, err)
+		}
+		return parcel.Entry{Meta: entryMeta("file", path), Data: data}, nil
 	}
 
-	parcel.ObjectHeaders = append(parcel.ObjectHeaders, objectHeader)
-
 	paths, err := listDirectory(path)
 	if err != nil {
-		processError("Not able to list directory "+path, err)
-	}
-
-	return dirIndex, paths //FIXME returning directory header index in headers list here (if not try to fall back to header hash or depth)
-}
-
-func processFile(parcel *model.Parcel, path string, parentDirectories []int) {
-	object, err := constructObject(path)
-	if err != nil {
-		processError("Error constructing object", err)
-	}
-
-	fileName := filepath.Base(path)
-	objectHeader, err := constructObjectHeader(object, fileName)
-	if err != nil {
-		processError("Error constructing object header", err)
-	}
-
-	parcel.ObjectHeaders = append(parcel.ObjectHeaders, objectHeader)
-	parcel.Objects = append(parcel.Objects, object)
-
-	if len(parentDirectories) == 0 {
-		// if it's not in directory, just simple file, finish here
-		return
-	}
-
-	hash, err := sha256(objectHeader)
-	if err != nil {
-		fmt.Printf("can't construct header hash for file %s due to error %v", path, err)
-		return
-	}
-
-	constructExternalReference(parcel, hash, objectHeader.ObjectSize, parentDirectories)
-}
-
-func constructExternalReference(parcel *model.Parcel, hash string, size uint64, parentDirectories []int) {
-	if len(parentDirectories) == 0 {
-		return
-	}
-
-	parentIndex := parentDirectories[len(parentDirectories)-1]
-	parentDirectoryHeader := parcel.ObjectHeaders[parentIndex]
-	parentDirectoryHeader.ExternalReferences = append(parentDirectoryHeader.ExternalReferences, hash)
-	parentDirectoryHeader.ExternalReferencesSize++
-	parentDirectoryHeader.RecursiveSizeTotal += size
-	parentDirectoryHeader.RecursiveSizeFirstLevel += size
-	parcel.ObjectHeaders[parentIndex] = parentDirectoryHeader
-
-	if size == 0 {
-		// no need to update senior parents if the size is 0
-		return
-	}
-
-	for i := len(parentDirectories) - 2; i >= 0; i-- {
-		upTheTreeIndex := parentDirectories[i]
-		parentDirectoryHeader := parcel.ObjectHeaders[upTheTreeIndex]
-		parentDirectoryHeader.RecursiveSizeTotal += size
-		parcel.ObjectHeaders[upTheTreeIndex] = parentDirectoryHeader
-	}
-}
-
-func constructObject(path string) (model.Object, error) {
-	object := model.Object{}
-	bytes, err := ioutil.ReadFile(path)
-	if err != nil {
-		return model.Object{}, fmt.Errorf("reading file: %v failed with error: %v", path, err)
-	}
-
-	object.Length = uint64(len(bytes))
-	object.Data = bytes
-	return object, nil
-}
-
-func constructObjectHeader(object model.Object, fileName string) (model.ObjectHeader, error) {
-	objectHeader := model.ObjectHeader{}
-	objectHash, err := sha256(object)
-	if err != nil {
-		return model.ObjectHeader{}, fmt.Errorf("hashing object faled due to err: %v", err)
-	}
-
-	metaStructure := model.Meta{
-		Key:   "type",
-		Value: "file",
-	}
-
-	metaDescription := model.Meta{
-		Key:   "name",
-		Value: fileName,
-	}
-
-	objectHeader.ObjectHash = objectHash
-	objectHeader.ObjectSize = object.Length
-	objectHeader.Meta = []model.Meta{metaStructure, metaDescription}
-
-	return objectHeader, nil
-}
-
-func constructDirHeader(dirName string) (model.ObjectHeader, error) {
-	objectHeader := model.ObjectHeader{}
-
-	metaStructure := model.Meta{
-		Key:   "type",
-		Value: "directory",
+		return parcel.Entry{}, fmt.Errorf("not able to list directory %s due to error %v", path, err)
 	}
-	metaDescription := model.Meta{
-		Key:   "name",
-		Value: dirName,
-	}
-
-	objectHeader.Meta = []model.Meta{metaStructure, metaDescription}
-
-	return objectHeader, nil
-}
-
-func constructRootHash(parcel model.Parcel) (model.RootHash, error) {
-	firstObjectHeaderHash, err := sha256(parcel.ObjectHeaders[0])
-	if err != nil {
-		return model.RootHash{}, fmt.Errorf("hashing first object header faled due to err: %v", err)
+	entry := parcel.Entry{Meta: entryMeta("directory", path)}
+	for _, subPath := range paths {
+		child, err := pathEntry(subPath)
+		if err != nil {
+			return parcel.Entry{}, err
+		}
+		entry.Children = append(entry.Children, child)
 	}
-
-	return model.RootHash{
-		Timestamp:        time.Now(),
-		ObjectHeaderHash: firstObjectHeaderHash,
-	}, nil
+	return entry, nil
 }
 
-func sha256(object interface{}) (string, error) {
-	bytes, err := json.Marshal(object)
-	if err != nil {
-		return "", err
+func entryMeta(entryType, path string) []model.Meta {
+	return []model.Meta{
+		{Key: "type", Value: entryType},
+		{Key: "name", Value: filepath.Base(path)},
 	}
-
-	sha256 := cipher.SumSHA256(bytes) //FIXME - for some reason cannot use same method from dmsgchipher package
-	return sha256.Hex(), nil
 }
 
 func isDirectory(path string) (bool, error) {
@@ -328,9 +192,3 @@ func listDirectory(path string) ([]string, error) {
 	}
 	return files, err
 }
-
-func processError(message string, err error) {
-	fmt.Println(message)
-	fmt.Println(err)
-	os.Exit(2)
-}
@@ -23,7 +23,7 @@ require (
 	github.com/spf13/cobra v0.0.6-0.20191014031137-8a4b46fadf75
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.4.1-0.20190904163530-85f2b59c4459 // indirect
-	go.etcd.io/bbolt v1.3.4-0.20191001164932-6e135e5d7e3d // indirect
+	go.etcd.io/bbolt v1.3.4-0.20191001164932-6e135e5d7e3d
 	golang.org/x/sys v0.0.0-20200107162124-548cf772de50 // indirect
 	gopkg.in/go-playground/validator.v9 v9.31.0 // indirect
 	gopkg.in/yaml.v2 v2.2.7
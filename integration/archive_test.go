@@ -0,0 +1,146 @@
+package integration
+
+import (
+	"bytes"
+	"testing"
+)
+
+// export - archive of the latest sequence of the publisher's feed stored on the node
+func (tn *testNode) export(publisher *testNode) []byte {
+	tn.t.Helper()
+	var archive bytes.Buffer
+	if err := tn.nodes.Export(publisher.publisher(), nil, &archive); err != nil {
+		tn.t.Fatalf("Exporting feed of: %v from node: %v failed due to error: %v", publisher.name, tn.name, err)
+	}
+	return archive.Bytes()
+}
+
+// checkStore - fail if node stores anything that isn't part of complete data of its latest sequences, returns
+// number of stored object headers
+func (tn *testNode) checkStore() int {
+	tn.t.Helper()
+	report, err := tn.service.Fsck(false)
+	if err != nil {
+		tn.t.Fatalf("Checking data store of node: %v failed due to error: %v", tn.name, err)
+	}
+	if len(report.DanglingReferences) > 0 || len(report.CorruptedHeaders) > 0 || len(report.CorruptedObjects) > 0 ||
+		len(report.OrphanedHeaders) > 0 || len(report.OrphanedObjects) > 0 || len(report.IncompleteRootHashes) > 0 {
+		tn.t.Fatalf("Data store of node: %v has problems: %+v", tn.name, report)
+	}
+	return report.ObjectHeaders
+}
+
+// feedSequence - sequence of the publisher's feed stored on the node, 0 if node doesn't store the feed
+func (tn *testNode) feedSequence(publisher *testNode) uint64 {
+	tn.t.Helper()
+	feeds, err := tn.nodes.Feeds()
+	if err != nil {
+		tn.t.Fatal(err)
+	}
+	for _, feed := range feeds {
+		if feed.Publisher == publisher.publisher() {
+			return feed.Sequence
+		}
+	}
+	return 0
+}
+
+func TestExportedFeedImportsIntoOtherNode(t *testing.T) {
+	n := newNetwork(t)
+	defer n.close()
+	publisher := n.addNode("publisher")
+	subscriber := n.addNode("subscriber")
+	// not subscribed, so it gets the feed only by import
+	importer := n.addNode("importer")
+
+	subscriber.subscribe(publisher)
+	published := publisher.publish(directory("shared",
+		file("first.txt", "first content"),
+		directory("nested", file("second.txt", "second content")),
+	))
+	expected := map[string]string{"shared/first.txt": "first content", "shared/nested/second.txt": "second content"}
+	subscriber.waitForFiles(publisher, expected)
+
+	archive := subscriber.export(publisher)
+	for i := 0; i < 2; i++ {
+		// importing the same archive again is accepted without storing anything
+		imported, err := importer.nodes.Import(bytes.NewReader(archive))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if imported.Key() != published.Key() || imported.Signature != published.Signature {
+			t.Fatalf("Imported root hash: %+v doesn't match published one: %+v", imported, published)
+		}
+	}
+	importer.waitForFiles(publisher, expected)
+	if headers, expectedHeaders := importer.checkStore(), subscriber.checkStore(); headers != expectedHeaders {
+		t.Fatalf("Node stores %v object headers after import, expected: %v", headers, expectedHeaders)
+	}
+}
+
+func TestInvalidArchiveIsRolledBack(t *testing.T) {
+	n := newNetwork(t)
+	defer n.close()
+	publisher := n.addNode("publisher")
+	subscriber := n.addNode("subscriber")
+	importer := n.addNode("importer")
+
+	subscriber.subscribe(publisher)
+	publisher.publish(directory("shared", file("kept.txt", "kept content"), file("changed.txt", "first content")))
+	subscriber.waitForFiles(publisher, map[string]string{"shared/kept.txt": "kept content",
+		"shared/changed.txt": "first content"})
+	first := subscriber.export(publisher)
+
+	publisher.publish(directory("shared", file("kept.txt", "kept content"), file("changed.txt", "second content")))
+	subscriber.waitForFiles(publisher, map[string]string{"shared/kept.txt": "kept content",
+		"shared/changed.txt": "second content"})
+	second := subscriber.export(publisher)
+
+	tests := []struct {
+		name    string
+		archive []byte
+	}{
+		{name: "truncated archive", archive: second[:len(second)/2]},
+		// object bytes don't match hash of the object any more, while headers preceding it are valid
+		{name: "tampered archive", archive: bytes.Replace(second, []byte("second content"), []byte("SECOND content"), 1)},
+	}
+
+	// nothing is left behind by import that fails before any sequence of the feed is stored
+	// harness of the node fails the whole test, so cases don't run as subtests
+	for _, tc := range tests {
+		if _, err := importer.nodes.Import(bytes.NewReader(tc.archive)); err == nil {
+			t.Fatalf("Invalid archive was imported: %v", tc.name)
+		}
+		if sequence := importer.feedSequence(publisher); sequence != 0 {
+			t.Fatalf("Node stores sequence %v of feed after failed import of %v", sequence, tc.name)
+		}
+		if headers := importer.checkStore(); headers != 0 {
+			t.Fatalf("Node stores %v object headers after failed import of %v", headers, tc.name)
+		}
+	}
+
+	if _, err := importer.nodes.Import(bytes.NewReader(first)); err != nil {
+		t.Fatal(err)
+	}
+	headers := importer.checkStore()
+
+	// headers shared with stored sequence are moved back to it
+	for _, tc := range tests {
+		if _, err := importer.nodes.Import(bytes.NewReader(tc.archive)); err == nil {
+			t.Fatalf("Invalid archive was imported: %v of next sequence", tc.name)
+		}
+		if sequence := importer.feedSequence(publisher); sequence != 1 {
+			t.Fatalf("Node stores sequence %v of feed after failed import of %v, expected previous one", sequence, tc.name)
+		}
+		if restored := importer.checkStore(); restored != headers {
+			t.Fatalf("Node stores %v object headers after failed import of %v, expected: %v", restored, tc.name, headers)
+		}
+	}
+
+	if _, err := importer.nodes.Import(bytes.NewReader(second)); err != nil {
+		t.Fatal(err)
+	}
+	importer.waitForFiles(publisher, map[string]string{"shared/kept.txt": "kept content",
+		"shared/changed.txt": "second content"})
+	importer.checkStore()
+}
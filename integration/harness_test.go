@@ -0,0 +1,396 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/cli/client"
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/node"
+	"github.com/SkycoinProject/cxo-2/pkg/node/data"
+	"github.com/SkycoinProject/cxo-2/pkg/parcel"
+	"github.com/SkycoinProject/cxo-2/pkg/signature"
+	"github.com/SkycoinProject/cxo-2/pkg/tracker/fake"
+	dmsghttp "github.com/SkycoinProject/dmsg-http"
+	"github.com/SkycoinProject/dmsg/cipher"
+	"github.com/SkycoinProject/dmsg/disc"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// syncTimeout - how long data is expected to take to reach subscribed nodes on one machine
+const syncTimeout = 10 * time.Second
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.ReleaseMode)
+	log.SetLevel(log.WarnLevel)
+	os.Exit(m.Run())
+}
+
+// network - fake tracker with nodes using it, all running in the test process over plain HTTP
+type network struct {
+	t       *testing.T
+	tracker *fake.Tracker
+	server  *httptest.Server
+	dir     string
+	nodes   []*testNode
+}
+
+func newNetwork(t *testing.T) *network {
+	dir, err := ioutil.TempDir("", "cxo-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := fake.New(http.DefaultClient)
+	return &network{t: t, tracker: tracker, server: httptest.NewServer(tracker), dir: dir}
+}
+
+// close - stop nodes and the tracker and remove data of all nodes
+func (n *network) close() {
+	for _, tn := range n.nodes {
+		tn.stop()
+		tn.app.server.Close()
+	}
+	n.server.Close()
+	if err := os.RemoveAll(n.dir); err != nil {
+		n.t.Logf("Removing test directory: %v failed due to error: %v", n.dir, err)
+	}
+}
+
+// testNode - node with file sharing app registered on it, storing feeds it is notified about in its directory
+type testNode struct {
+	t       *testing.T
+	name    string
+	config  config.Config
+	service *node.Service
+	app     *app
+	nodes   *client.NodeClient
+	tracker *client.TrackerClient
+	stop    func()
+}
+
+// addNode - start node with data stored in its own temp directory, which waits for trackers and apps on random
+// local ports
+func (n *network) addNode(name string, configure ...func(*config.Config)) *testNode {
+	n.t.Helper()
+	dir := filepath.Join(n.dir, name)
+	pubKey, secKey := cipher.GenerateKeyPair()
+	cfg := config.Config{
+		Trackers: []config.TrackerConfig{{Address: n.server.URL}},
+		PubKey:   pubKey,
+		SecKey:   secKey,
+		Server: config.ServerConfig{
+			Transport: config.HTTPTransport,
+			Address:   freeAddress(n.t),
+		},
+		APIAddress: freeAddress(n.t),
+		Discovery:  disc.NewHTTP(dmsghttp.DefaultDiscoveryURL),
+		Storage: config.StorageConfig{
+			Backend:      config.FilesystemStorage,
+			DatabasePath: filepath.Join(dir, "cxo-node.db"),
+			ObjectsPath:  filepath.Join(dir, "objects"),
+		},
+		Quota: config.QuotaConfig{Policy: config.KeepPreviousPolicy},
+		Trust: config.TrustConfig{TrackerHosts: []string{"127.0.0.1"}},
+	}
+	for _, c := range configure {
+		c(&cfg)
+	}
+
+	tn := &testNode{
+		t:       n.t,
+		name:    name,
+		config:  cfg,
+		app:     newApp(n.t, filepath.Join(dir, "files")),
+		nodes:   client.NewNodeClient(cfg),
+		tracker: client.NewTrackerClient(cfg, log.StandardLogger()),
+	}
+	tn.start()
+	n.nodes = append(n.nodes, tn)
+	return tn
+}
+
+// start - run node on its data store until it's stopped, the same way as cxo-node does
+func (tn *testNode) start() {
+	tn.t.Helper()
+	db, tearDown, err := data.Open(tn.config.Storage, tn.logger())
+	if err != nil {
+		tn.t.Fatalf("Opening data store of node: %v failed due to error: %v", tn.name, err)
+	}
+	if err := db.RegisterApp(tn.app.address, "File Transfer App"); err != nil {
+		tn.t.Fatalf("Registering app on node: %v failed due to error: %v", tn.name, err)
+	}
+
+	tn.service = node.NewService(tn.config, db, tn.logger())
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		if err := tn.service.Run(ctx); err != nil {
+			tn.t.Errorf("Running node: %v failed due to error: %v", tn.name, err)
+		}
+		tearDown()
+	}()
+	tn.stop = func() {
+		cancel()
+		<-stopped
+		tn.stop = func() {}
+	}
+	tn.waitForReady()
+}
+
+// logger - standard logger with the node's name, so entries of nodes sharing the process can be told apart
+func (tn *testNode) logger() log.FieldLogger {
+	return log.WithField("node", tn.name)
+}
+
+// waitForReady - wait until node reports it's ready, instead of sleeping for fixed time
+func (tn *testNode) waitForReady() {
+	tn.t.Helper()
+	var health model.Health
+	deadline := time.Now().Add(syncTimeout)
+	for time.Now().Before(deadline) {
+		var err error
+		if health, err = tn.nodes.Ready(); err == nil && health.Status == model.HealthOK {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	tn.t.Fatalf("Node: %v isn't ready, last checks: %v", tn.name, health.Checks)
+}
+
+// metric - value of metric series without labels scraped from the node's metrics endpoint
+func (tn *testNode) metric(name string) float64 {
+	tn.t.Helper()
+	resp, err := http.Get(fmt.Sprint("http://", tn.config.APIAddress, "/metrics"))
+	if err != nil {
+		tn.t.Fatalf("Scraping metrics of node: %v failed due to error: %v", tn.name, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		tn.t.Fatalf("Reading metrics of node: %v failed due to error: %v", tn.name, err)
+	}
+
+	for _, line := range strings.Split(string(body), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == name {
+			value, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				tn.t.Fatalf("Metric: %v of node: %v has invalid value: %v", name, tn.name, fields[1])
+			}
+			return value
+		}
+	}
+	tn.t.Fatalf("Node: %v doesn't expose metric: %v", tn.name, name)
+	return 0
+}
+
+// publisher - public key of the node, used as publisher of the data it publishes
+func (tn *testNode) publisher() string {
+	return tn.config.PubKey.Hex()
+}
+
+// subscribe - subscribe the same way as CLI does, on the node first and on the tracker then
+func (tn *testNode) subscribe(publisher *testNode) {
+	tn.t.Helper()
+	if err := tn.nodes.Subscribe(publisher.publisher()); err != nil {
+		tn.t.Fatalf("Subscribing node: %v to: %v failed due to error: %v", tn.name, publisher.name, err)
+	}
+	if err := tn.tracker.Subscribe(publisher.publisher()); err != nil {
+		tn.t.Fatalf("Subscribing node: %v to: %v on tracker failed due to error: %v", tn.name, publisher.name, err)
+	}
+}
+
+// publish - publish entry the same way as file sharing CLI does, with the next sequence taken from the tracker
+func (tn *testNode) publish(root parcel.Entry) model.RootHash {
+	tn.t.Helper()
+	feedParcel, hash, err := parcel.Build(root)
+	if err != nil {
+		tn.t.Fatalf("Building parcel failed due to error: %v", err)
+	}
+	sequence, err := tn.tracker.GetNewSequenceNumber(tn.publisher())
+	if err != nil {
+		tn.t.Fatalf("Getting next sequence of node: %v failed due to error: %v", tn.name, err)
+	}
+
+	rootHash := model.RootHash{
+		Publisher:        tn.publisher(),
+		SignatureVersion: signature.RootHashVersion,
+		Sequence:         sequence,
+		Timestamp:        time.Now(),
+		ObjectHeaderHash: hash,
+	}
+	if rootHash.Signature, err = signature.SignRootHash(rootHash, tn.config.PubKey, tn.config.SecKey); err != nil {
+		tn.t.Fatalf("Signing root hash failed due to error: %v", err)
+	}
+	if err := tn.tracker.PublishData(model.PublishDataRequest{RootHash: rootHash, Parcel: feedParcel}); err != nil {
+		tn.t.Fatalf("Publishing data of node: %v failed due to error: %v", tn.name, err)
+	}
+	return rootHash
+}
+
+// waitForFiles - wait until app of the node stores exactly given files of the publisher, keyed by path relative
+// to the publisher's directory
+func (tn *testNode) waitForFiles(publisher *testNode, expected map[string]string) {
+	tn.t.Helper()
+	var files map[string]string
+	deadline := time.Now().Add(syncTimeout)
+	for time.Now().Before(deadline) {
+		files = tn.app.files(publisher.publisher())
+		if equalFiles(files, expected) {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	tn.t.Fatalf("Node: %v didn't sync data of: %v, expected: %v, got: %v", tn.name, publisher.name, expected, files)
+}
+
+func equalFiles(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, content := range a {
+		if other, ok := b[path]; !ok || other != content {
+			return false
+		}
+	}
+	return true
+}
+
+// file - entry of single file, as created by file sharing CLI
+func file(name, content string) parcel.Entry {
+	return parcel.Entry{Meta: entryMeta("file", name), Data: []byte(content)}
+}
+
+// directory - entry of directory with its files and subdirectories, as created by file sharing CLI
+func directory(name string, children ...parcel.Entry) parcel.Entry {
+	return parcel.Entry{Meta: entryMeta("directory", name), Children: children}
+}
+
+func entryMeta(entryType, name string) []model.Meta {
+	return []model.Meta{
+		{Key: "type", Value: entryType},
+		{Key: "name", Value: name},
+	}
+}
+
+// app - file sharing app storing the latest data of each publisher in its directory, replacing previous one
+type app struct {
+	t       *testing.T
+	dir     string
+	server  *httptest.Server
+	address string
+}
+
+func newApp(t *testing.T, dir string) *app {
+	a := &app{t: t, dir: dir}
+	a.server = httptest.NewServer(http.HandlerFunc(a.notifyHandler))
+	a.address = fmt.Sprint(a.server.Listener.Addr().String(), "/notify")
+	return a
+}
+
+func (a *app) notifyHandler(w http.ResponseWriter, r *http.Request) {
+	var req model.NotifyAppRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	feed, err := parcel.NewFeed(model.PublishDataRequest{RootHash: req.RootHash, Parcel: req.Parcel})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// files are written to temporary directory first, so that partially written feed is never observed
+	path := filepath.Join(a.dir, req.RootHash.Publisher)
+	tmp := path + ".tmp"
+	if err := a.store(feed, req.RootHash.ObjectHeaderHash, tmp); err != nil {
+		a.t.Errorf("Storing data of publisher: %v failed due to error: %v", req.RootHash.Publisher, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := os.RemoveAll(path); err != nil {
+		a.t.Errorf("Removing previous data of publisher: %v failed due to error: %v", req.RootHash.Publisher, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		a.t.Errorf("Storing data of publisher: %v failed due to error: %v", req.RootHash.Publisher, err)
+	}
+}
+
+func (a *app) store(feed parcel.Feed, hash, dir string) error {
+	header, err := feed.GetObjectHeader(hash)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var entryType, name string
+	for _, meta := range header.Meta {
+		switch meta.Key {
+		case "type":
+			entryType = meta.Value
+		case "name":
+			name = meta.Value
+		}
+	}
+	path := filepath.Join(dir, name)
+	if entryType == "directory" {
+		for _, ref := range header.ExternalReferences {
+			if err := a.store(feed, ref, path); err != nil {
+				return err
+			}
+		}
+		return os.MkdirAll(path, 0755)
+	}
+
+	object, err := feed.GetObject(header.ObjectHash)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, object.Data, 0644)
+}
+
+// files - content of files stored for the publisher, keyed by path relative to the publisher's directory
+func (a *app) files(publisher string) map[string]string {
+	root := filepath.Join(a.dir, publisher)
+	files := make(map[string]string)
+	_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		files[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	return files
+}
+
+// freeAddress - local address with port that is free at the moment
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
@@ -1,2 +0,0 @@
-rm -rf ./files/node-*/* -y
-docker rm -f node-1 node-2 node-3 node-4 node-5
@@ -0,0 +1,139 @@
+package integration
+
+import (
+	"testing"
+)
+
+func TestSubscribedNodeReceivesPublishedFile(t *testing.T) {
+	n := newNetwork(t)
+	defer n.close()
+	publisher := n.addNode("publisher")
+	subscriber := n.addNode("subscriber")
+
+	subscriber.subscribe(publisher)
+	publisher.publish(file("content.txt", "first content"))
+
+	subscriber.waitForFiles(publisher, map[string]string{"content.txt": "first content"})
+
+	status, err := subscriber.nodes.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Feeds) != 1 || status.Feeds[0].Publisher != publisher.publisher() || status.Feeds[0].Sequence != 1 {
+		t.Fatalf("Status doesn't report synced feed, got: %+v", status.Feeds)
+	}
+	// download is complete before app is notified
+	if len(status.Downloads) != 0 {
+		t.Fatalf("Status reports unfinished downloads after sync, got: %+v", status.Downloads)
+	}
+}
+
+func TestNewSequenceReplacesPreviousData(t *testing.T) {
+	n := newNetwork(t)
+	defer n.close()
+	publisher := n.addNode("publisher")
+	subscriber := n.addNode("subscriber")
+
+	subscriber.subscribe(publisher)
+	publisher.publish(directory("shared", file("first.txt", "first content")))
+	subscriber.waitForFiles(publisher, map[string]string{"shared/first.txt": "first content"})
+
+	publisher.publish(directory("shared",
+		file("first.txt", "updated content"),
+		file("second.txt", "second content"),
+		directory("nested", file("third.txt", "third content")),
+	))
+	subscriber.waitForFiles(publisher, map[string]string{
+		"shared/first.txt":        "updated content",
+		"shared/second.txt":       "second content",
+		"shared/nested/third.txt": "third content",
+	})
+
+	// app is notified only after data of the previous sequence is removed
+	if fetched := subscriber.metric("cxo_node_objects_fetched_total"); fetched == 0 {
+		t.Fatal("Metrics don't report fetched objects")
+	}
+	if reclaimed := subscriber.metric("cxo_node_gc_reclaimed_bytes_total"); reclaimed < float64(len("first content")) {
+		t.Fatalf("Metrics report %v reclaimed bytes, expected at least content of the replaced file", reclaimed)
+	}
+}
+
+func TestLateSubscriberReceivesLatestSequence(t *testing.T) {
+	n := newNetwork(t)
+	defer n.close()
+	publisher := n.addNode("publisher")
+	early := n.addNode("early")
+
+	early.subscribe(publisher)
+	publisher.publish(file("content.txt", "first content"))
+	publisher.publish(file("content.txt", "second content"))
+	early.waitForFiles(publisher, map[string]string{"content.txt": "second content"})
+
+	late := n.addNode("late")
+	late.subscribe(publisher)
+	late.waitForFiles(publisher, map[string]string{"content.txt": "second content"})
+}
+
+func TestNodesReceiveOnlySubscribedFeeds(t *testing.T) {
+	n := newNetwork(t)
+	defer n.close()
+	first := n.addNode("first")
+	second := n.addNode("second")
+	both := n.addNode("both")
+	one := n.addNode("one")
+
+	both.subscribe(first)
+	both.subscribe(second)
+	one.subscribe(first)
+	// subscribed on tracker only, so node refuses root hashes it is notified about
+	if err := one.tracker.Subscribe(second.publisher()); err != nil {
+		t.Fatal(err)
+	}
+
+	first.publish(file("first.txt", "first content"))
+	second.publish(directory("second", file("second.txt", "second content")))
+
+	both.waitForFiles(first, map[string]string{"first.txt": "first content"})
+	both.waitForFiles(second, map[string]string{"second/second.txt": "second content"})
+	one.waitForFiles(first, map[string]string{"first.txt": "first content"})
+
+	// refused notification is answered before tracker is done notifying, so nothing can arrive after this
+	n.tracker.Wait()
+	if files := one.app.files(second.publisher()); len(files) != 0 {
+		t.Fatalf("Node received data of feed it isn't subscribed to: %v", files)
+	}
+	feeds, err := one.nodes.Feeds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, feed := range feeds {
+		if feed.Publisher == second.publisher() {
+			t.Fatalf("Node stored feed it isn't subscribed to: %+v", feed)
+		}
+	}
+}
+
+func TestRestartedNodeKeepsDataAndReceivesNewSequences(t *testing.T) {
+	n := newNetwork(t)
+	defer n.close()
+	publisher := n.addNode("publisher")
+	subscriber := n.addNode("subscriber")
+
+	subscriber.subscribe(publisher)
+	first := publisher.publish(file("content.txt", "first content"))
+	subscriber.waitForFiles(publisher, map[string]string{"content.txt": "first content"})
+
+	subscriber.stop()
+	subscriber.start()
+
+	feeds, err := subscriber.nodes.Feeds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 1 || feeds[0].Publisher != publisher.publisher() || feeds[0].Sequence != first.Sequence {
+		t.Fatalf("Restarted node doesn't keep stored feed, got: %+v", feeds)
+	}
+
+	publisher.publish(file("content.txt", "second content"))
+	subscriber.waitForFiles(publisher, map[string]string{"content.txt": "second content"})
+}
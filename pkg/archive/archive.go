@@ -0,0 +1,189 @@
+// Package archive - self-contained snapshot of a single feed sequence, stored as tar file.
+//
+// Archive starts with "rootHash.json" holding signed root hash, followed by "headers/<hash>.json" entries with
+// marshalled object headers and "objects/<hash>" entries with raw object bytes. Object length is kept in the
+// "CXO.length" PAX record of object entry, since object hash covers it as well as object bytes.
+package archive
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+)
+
+const (
+	rootHashName    = "rootHash.json"
+	headersDir      = "headers/"
+	objectsDir      = "objects/"
+	headerExtension = ".json"
+	lengthRecord    = "CXO.length"
+	// nil and empty object data are marshalled differently, so they hash differently too
+	nilDataRecord = "CXO.nildata"
+)
+
+// EntryType - kind of data stored in archive entry
+type EntryType int
+
+const (
+	// RootHashEntry - signed root hash of the feed sequence
+	RootHashEntry EntryType = iota
+	// ObjectHeaderEntry - object header addressed by hash
+	ObjectHeaderEntry
+	// ObjectEntry - object addressed by hash
+	ObjectEntry
+)
+
+// Entry - single item read from archive. Only the field matching entry type is set.
+type Entry struct {
+	Type         EntryType
+	Hash         string
+	RootHash     model.RootHash
+	ObjectHeader model.ObjectHeader
+	Object       model.Object
+}
+
+// Writer - writes feed snapshot to archive. Root hash has to be written first.
+type Writer struct {
+	tw        *tar.Writer
+	timestamp time.Time
+	started   bool
+}
+
+// NewWriter - create archive writer on top of w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{tw: tar.NewWriter(w), timestamp: time.Now()}
+}
+
+// WriteRootHash - write root hash of the archived feed sequence
+func (w *Writer) WriteRootHash(rootHash model.RootHash) error {
+	if w.started {
+		return fmt.Errorf("root hash has to be the first entry of archive")
+	}
+	w.started = true
+	b, err := json.Marshal(rootHash)
+	if err != nil {
+		return fmt.Errorf("marshal root hash failed due to error: %v", err)
+	}
+	return w.write(rootHashName, b, nil)
+}
+
+// WriteObjectHeader - write object header with given hash
+func (w *Writer) WriteObjectHeader(hash string, header model.ObjectHeader) error {
+	if !w.started {
+		return fmt.Errorf("root hash has to be the first entry of archive")
+	}
+	b, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("marshal object header with hash: %v failed due to error: %v", hash, err)
+	}
+	return w.write(headersDir+hash+headerExtension, b, nil)
+}
+
+// WriteObject - write object with given hash
+func (w *Writer) WriteObject(hash string, object model.Object) error {
+	if !w.started {
+		return fmt.Errorf("root hash has to be the first entry of archive")
+	}
+	records := map[string]string{lengthRecord: strconv.FormatUint(object.Length, 10)}
+	if object.Data == nil {
+		records[nilDataRecord] = "true"
+	}
+	return w.write(objectsDir+hash, object.Data, records)
+}
+
+// Close - finish the archive, underlying writer is not closed
+func (w *Writer) Close() error {
+	return w.tw.Close()
+}
+
+func (w *Writer) write(name string, data []byte, records map[string]string) error {
+	hdr := &tar.Header{
+		Typeflag:   tar.TypeReg,
+		Name:       name,
+		Mode:       0600,
+		Size:       int64(len(data)),
+		ModTime:    w.timestamp,
+		PAXRecords: records,
+	}
+	if records != nil {
+		hdr.Format = tar.FormatPAX
+	}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("writing archive entry: %v failed due to error: %v", name, err)
+	}
+	if _, err := w.tw.Write(data); err != nil {
+		return fmt.Errorf("writing archive entry: %v failed due to error: %v", name, err)
+	}
+	return nil
+}
+
+// Reader - reads feed snapshot from archive one entry at a time, so archived objects are never all in memory
+type Reader struct {
+	tr      *tar.Reader
+	started bool
+}
+
+// NewReader - create archive reader on top of r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{tr: tar.NewReader(r)}
+}
+
+// Next - read next archive entry, io.EOF is returned once all entries are read
+func (r *Reader) Next() (Entry, error) {
+	hdr, err := r.tr.Next()
+	if err != nil {
+		if err == io.EOF && !r.started {
+			return Entry{}, fmt.Errorf("archive doesn't contain root hash")
+		}
+		return Entry{}, err
+	}
+	if hdr.Typeflag != tar.TypeReg {
+		return Entry{}, fmt.Errorf("unexpected archive entry: %v", hdr.Name)
+	}
+
+	data, err := ioutil.ReadAll(r.tr)
+	if err != nil {
+		return Entry{}, fmt.Errorf("reading archive entry: %v failed due to error: %v", hdr.Name, err)
+	}
+
+	if !r.started {
+		if hdr.Name != rootHashName {
+			return Entry{}, fmt.Errorf("archive has to start with %v, got: %v", rootHashName, hdr.Name)
+		}
+		r.started = true
+		entry := Entry{Type: RootHashEntry}
+		if err := json.Unmarshal(data, &entry.RootHash); err != nil {
+			return Entry{}, fmt.Errorf("invalid root hash in archive: %v", err)
+		}
+		return entry, nil
+	}
+
+	dir, name := path.Split(hdr.Name)
+	switch {
+	case dir == headersDir && strings.HasSuffix(name, headerExtension):
+		entry := Entry{Type: ObjectHeaderEntry, Hash: strings.TrimSuffix(name, headerExtension)}
+		if err := json.Unmarshal(data, &entry.ObjectHeader); err != nil {
+			return Entry{}, fmt.Errorf("invalid object header: %v in archive: %v", entry.Hash, err)
+		}
+		return entry, nil
+	case dir == objectsDir && name != "":
+		length, err := strconv.ParseUint(hdr.PAXRecords[lengthRecord], 10, 64)
+		if err != nil {
+			return Entry{}, fmt.Errorf("invalid length of object: %v in archive: %v", name, err)
+		}
+		if hdr.PAXRecords[nilDataRecord] == "true" {
+			data = nil
+		}
+		return Entry{Type: ObjectEntry, Hash: name, Object: model.Object{Length: length, Data: data}}, nil
+	default:
+		return Entry{}, fmt.Errorf("unexpected archive entry: %v", hdr.Name)
+	}
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/SkycoinProject/cxo-2/pkg/cli/client"
+	"github.com/spf13/cobra"
+)
+
+func exportCmd(client *client.NodeClient) *cobra.Command {
+	var sequence uint64
+	var output string
+
+	exportCmd := &cobra.Command{
+		Short:        "Export feed to archive file",
+		Use:          "export [flags] [public_key]",
+		Long:         "Export root hash of the feed with all of its object headers and objects from the local node to single archive file",
+		SilenceUsage: true,
+		Args:         cobra.MinimumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			pubKey := args[0]
+			if pubKey == "" {
+				return c.Help()
+			}
+
+			var seq *uint64
+			if c.Flags().Changed("sequence") {
+				seq = &sequence
+			}
+			if output == "" {
+				output = pubKey + ".tar"
+			}
+
+			f, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("creating archive file failed due to error: %v", err)
+			}
+			if err := client.Export(pubKey, seq, f); err != nil {
+				_ = f.Close()
+				_ = os.Remove(output)
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("writing archive file failed due to error: %v", err)
+			}
+
+			fmt.Println("Feed exported to: ", output)
+			return nil
+		},
+	}
+
+	exportCmd.Flags().Uint64Var(&sequence, "sequence", 0, "sequence to export, latest by default")
+	exportCmd.Flags().StringVarP(&output, "output", "o", "", "archive file path, <public_key>.tar by default")
+	return exportCmd
+}
+
+func importCmd(client *client.NodeClient) *cobra.Command {
+	importCmd := &cobra.Command{
+		Short:                 "Import feed from archive file",
+		Use:                   "import [flags] [path_to_archive]",
+		Long:                  "Verify feed exported to archive file and load it into the local node",
+		SilenceUsage:          true,
+		Args:                  cobra.MinimumNArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			filePath := args[0]
+			if filePath == "" {
+				return c.Help()
+			}
+
+			f, err := os.Open(filePath)
+			if err != nil {
+				return fmt.Errorf("could not read archive file: %v", err)
+			}
+			defer f.Close()
+
+			rootHash, err := client.Import(f)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Imported root hash with key: ", rootHash.Key())
+			return nil
+		},
+	}
+
+	return importCmd
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/SkycoinProject/cxo-2/pkg/cli/client"
+	"github.com/spf13/cobra"
+)
+
+func blockCmd(client *client.NodeClient) *cobra.Command {
+	blockCmd := &cobra.Command{
+		Short:                 "Block public key",
+		Use:                   "block [public_key]",
+		Long:                  "Make the local node refuse root hashes of the publisher even if it's subscribed",
+		SilenceUsage:          true,
+		Args:                  cobra.MinimumNArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			pubKey := args[0]
+			if pubKey == "" {
+				return c.Help()
+			}
+
+			if err := client.Block(pubKey); err != nil {
+				return err
+			}
+
+			fmt.Println("Blocked: ", pubKey)
+			return nil
+		},
+	}
+
+	return blockCmd
+}
+
+func unblockCmd(client *client.NodeClient) *cobra.Command {
+	unblockCmd := &cobra.Command{
+		Short:                 "Unblock public key",
+		Use:                   "unblock [public_key]",
+		Long:                  "Remove the publisher from block list of the local node, its root hashes are accepted again if it's subscribed",
+		SilenceUsage:          true,
+		Args:                  cobra.MinimumNArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			pubKey := args[0]
+			if pubKey == "" {
+				return c.Help()
+			}
+
+			if err := client.Unblock(pubKey); err != nil {
+				return err
+			}
+
+			fmt.Println("Unblocked: ", pubKey)
+			return nil
+		},
+	}
+
+	return unblockCmd
+}
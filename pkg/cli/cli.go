@@ -6,6 +6,7 @@ import (
 
 	"github.com/SkycoinProject/cxo-2/pkg/cli/client"
 	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/logging"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -14,7 +15,8 @@ const version = "0.1.1"
 
 // NewCLI creates a cli instance
 func NewCLI(cfg config.Config) (*cobra.Command, error) {
-	c := client.NewTrackerClient(cfg)
+	c := client.NewTrackerClient(cfg, logging.New(cfg.Log))
+	nc := client.NewNodeClient(cfg)
 
 	cxoNodeCLI := &cobra.Command{
 		Short: fmt.Sprintf("The cxo-node command line interface"),
@@ -22,8 +24,18 @@ func NewCLI(cfg config.Config) (*cobra.Command, error) {
 	}
 
 	commands := []*cobra.Command{
-		subscribeCmd(c),
+		subscribeCmd(c, nc),
+		unsubscribeCmd(nc),
+		subscriptionsCmd(nc),
+		blockCmd(nc),
+		unblockCmd(nc),
 		publishDataCmd(c, cfg),
+		exportCmd(nc),
+		importCmd(nc),
+		feedsCmd(nc),
+		resumeCmd(nc),
+		verifyCmd(),
+		statusCmd(nc),
 	}
 
 	cxoNodeCLI.Version = version
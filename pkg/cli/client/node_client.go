@@ -0,0 +1,222 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+)
+
+// apiRoute - prefix of the local API routes
+const apiRoute = "/api/v1"
+
+// readyRoute - readiness check of the node, outside of API routes
+const readyRoute = "/readyz"
+
+const (
+	exportRoute = "/feeds/%s/export"
+	importRoute = "/import"
+	feedsRoute  = "/feeds"
+	statusRoute = "/status"
+	resumeRoute = "/feeds/%s/resume"
+
+	subscriptionsRoute = "/subscriptions"
+	subscriptionRoute  = "/subscriptions/%s"
+	blockedRoute       = "/blocked"
+	blockedKeyRoute    = "/blocked/%s"
+)
+
+// NodeClient - client of the local node API
+type NodeClient struct {
+	client  *http.Client
+	host    string
+	address string
+}
+
+// NewNodeClient - create client of the node running on the same machine
+func NewNodeClient(cfg config.Config) *NodeClient {
+	host := fmt.Sprint("http://", cfg.APIAddress)
+	return &NodeClient{
+		client:  http.DefaultClient,
+		host:    host,
+		address: fmt.Sprint(host, apiRoute),
+	}
+}
+
+// Export - write archive with root hash of the publisher's feed and all of its data to w. Latest sequence is
+// exported if sequence is not set.
+func (n *NodeClient) Export(publicKey string, sequence *uint64, w io.Writer) error {
+	url := fmt.Sprint(n.address, fmt.Sprintf(exportRoute, publicKey))
+	if sequence != nil {
+		url = fmt.Sprint(url, "?sequence=", *sequence)
+	}
+
+	resp, err := n.client.Get(url)
+	if err != nil {
+		return fmt.Errorf("export request failed due to error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("export", resp)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("reading exported archive failed due to error: %v", err)
+	}
+	return nil
+}
+
+// Import - load archive created by Export into the node, imported root hash is returned
+func (n *NodeClient) Import(r io.Reader) (model.RootHash, error) {
+	var rootHash model.RootHash
+	resp, err := n.client.Post(fmt.Sprint(n.address, importRoute), "application/x-tar", r)
+	if err != nil {
+		return rootHash, fmt.Errorf("import request failed due to error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return rootHash, responseError("import", resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rootHash); err != nil {
+		return rootHash, fmt.Errorf("reading import response failed due to error: %v", err)
+	}
+	return rootHash, nil
+}
+
+// Feeds - state of every feed known to the node
+func (n *NodeClient) Feeds() ([]model.FeedStatus, error) {
+	var feeds []model.FeedStatus
+	resp, err := n.client.Get(fmt.Sprint(n.address, feedsRoute))
+	if err != nil {
+		return feeds, fmt.Errorf("feeds request failed due to error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return feeds, responseError("feeds", resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&feeds); err != nil {
+		return feeds, fmt.Errorf("reading feeds response failed due to error: %v", err)
+	}
+	return feeds, nil
+}
+
+// Resume - make feed refused for exceeding quota active again
+func (n *NodeClient) Resume(publicKey string) error {
+	resp, err := n.client.Post(fmt.Sprint(n.address, fmt.Sprintf(resumeRoute, publicKey)), "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("resume request failed due to error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("resume", resp)
+	}
+	return nil
+}
+
+// Subscribe - make the node accept root hashes of the publisher
+func (n *NodeClient) Subscribe(publicKey string) error {
+	return n.updateTrust("subscribe", http.MethodPost, fmt.Sprintf(subscriptionRoute, publicKey))
+}
+
+// Unsubscribe - make the node refuse root hashes of the publisher
+func (n *NodeClient) Unsubscribe(publicKey string) error {
+	return n.updateTrust("unsubscribe", http.MethodDelete, fmt.Sprintf(subscriptionRoute, publicKey))
+}
+
+// Block - make the node refuse root hashes of the publisher even if it's subscribed
+func (n *NodeClient) Block(publicKey string) error {
+	return n.updateTrust("block", http.MethodPost, fmt.Sprintf(blockedKeyRoute, publicKey))
+}
+
+// Unblock - remove the publisher from block list of the node
+func (n *NodeClient) Unblock(publicKey string) error {
+	return n.updateTrust("unblock", http.MethodDelete, fmt.Sprintf(blockedKeyRoute, publicKey))
+}
+
+// Subscriptions - publishers whose root hashes are accepted by the node
+func (n *NodeClient) Subscriptions() ([]model.Subscription, error) {
+	var subscriptions []model.Subscription
+	err := n.getJSON("subscriptions", subscriptionsRoute, &subscriptions)
+	return subscriptions, err
+}
+
+// BlockedPublishers - publishers whose root hashes are refused by the node
+func (n *NodeClient) BlockedPublishers() ([]model.BlockedPublisher, error) {
+	var blocked []model.BlockedPublisher
+	err := n.getJSON("blocked publishers", blockedRoute, &blocked)
+	return blocked, err
+}
+
+// Status - summary of node readiness, trackers, stored feeds and unfinished downloads
+func (n *NodeClient) Status() (model.NodeStatus, error) {
+	var status model.NodeStatus
+	err := n.getJSON("status", statusRoute, &status)
+	return status, err
+}
+
+// Ready - readiness of the node, error is returned only if node can't be reached or its response can't be read
+func (n *NodeClient) Ready() (model.Health, error) {
+	var health model.Health
+	resp, err := n.client.Get(fmt.Sprint(n.host, readyRoute))
+	if err != nil {
+		return health, fmt.Errorf("readiness request failed due to error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return health, fmt.Errorf("reading readiness response failed due to error: %v", err)
+	}
+	return health, nil
+}
+
+func (n *NodeClient) updateTrust(request, method, route string) error {
+	req, err := http.NewRequest(method, fmt.Sprint(n.address, route), nil)
+	if err != nil {
+		return fmt.Errorf("creating %s request failed due to error: %v", request, err)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed due to error: %v", request, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(request, resp)
+	}
+	return nil
+}
+
+func (n *NodeClient) getJSON(request, route string, result interface{}) error {
+	resp, err := n.client.Get(fmt.Sprint(n.address, route))
+	if err != nil {
+		return fmt.Errorf("%s request failed due to error: %v", request, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(request, resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("reading %s response failed due to error: %v", request, err)
+	}
+	return nil
+}
+
+// responseError - error with message from node error response, or just status if body can't be read
+func responseError(request string, resp *http.Response) error {
+	var errResp struct {
+		Error string `json:"message"`
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err == nil && json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
+		return fmt.Errorf("%s request failed with status %v: %v", request, resp.Status, errResp.Error)
+	}
+	return fmt.Errorf("%s request failed with status %v", request, resp.Status)
+}
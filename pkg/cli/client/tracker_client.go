@@ -9,23 +9,28 @@ import (
 	"net/http"
 
 	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/logging"
 	"github.com/SkycoinProject/cxo-2/pkg/model"
-	dmsghttp "github.com/SkycoinProject/dmsg-http"
+	"github.com/SkycoinProject/cxo-2/pkg/tracker"
+	"github.com/SkycoinProject/cxo-2/pkg/transport"
 	"github.com/SkycoinProject/dmsg/cipher"
+	log "github.com/sirupsen/logrus"
 )
 
 type TrackerClient struct {
 	client           *http.Client
-	trackerAddress   string
+	trackers         *tracker.Pool
 	subscribeAddress string
+	logger           log.FieldLogger
 }
 
-func NewTrackerClient(cfg config.Config) *TrackerClient {
+func NewTrackerClient(cfg config.Config, logger log.FieldLogger) *TrackerClient {
 	sPK, sSK := cipher.GenerateKeyPair()
 	return &TrackerClient{
-		client:           dmsghttp.DMSGClient(cfg.Discovery, sPK, sSK),
-		trackerAddress:   cfg.TrackerAddress,
-		subscribeAddress: fmt.Sprintf("%v:%v/notify", cfg.PubKey.Hex(), cfg.Port), //FIXME - read route from node service
+		client:           transport.NewClient(cfg.Discovery, sPK, sSK),
+		trackers:         tracker.NewPool(cfg.Trackers, logger),
+		subscribeAddress: transport.Address(cfg) + "/notify", //FIXME - read route from node service
+		logger:           logger,
 	}
 }
 
@@ -35,22 +40,42 @@ const (
 	publishDataRoute  = "/data"
 )
 
+// Subscribe - subscribe on every tracker, so that node is notified by whichever tracker receives the data. Error is
+// returned only if subscribing fails on all of them.
 func (t *TrackerClient) Subscribe(publicKey string) error {
-	url := fmt.Sprint(t.trackerAddress, subscribeRoute, publicKey)
+	var err error
+	subscribed := false
+	for _, address := range t.trackers.Addresses() {
+		if err = t.subscribe(address, publicKey); err != nil {
+			t.logger.WithFields(log.Fields{logging.TrackerField: address, logging.PublisherField: publicKey}).
+				WithError(err).Warn("Subscribing on tracker failed")
+			continue
+		}
+		subscribed = true
+	}
+	if !subscribed {
+		return err
+	}
+	return nil
+}
+
+func (t *TrackerClient) subscribe(address, publicKey string) error {
+	url := fmt.Sprint(address, subscribeRoute, publicKey)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return fmt.Errorf("error creating subscribe request to public key: %v ", publicKey)
 	}
 
-	fmt.Println("subscribe address: ", t.subscribeAddress)
 	req.Header.Set("Address", t.subscribeAddress)
 
 	resp, err := t.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("subscribe request failed due to error: %v", err)
 	}
+	_ = resp.Body.Close()
 
-	fmt.Println("Subscribe response status: ", resp.Status)
+	t.logger.WithFields(log.Fields{logging.TrackerField: address, logging.PublisherField: publicKey}).
+		Debugf("Subscribed with address: %v, tracker returned status: %v", t.subscribeAddress, resp.Status)
 	return nil
 }
 
@@ -59,29 +84,44 @@ func (t *TrackerClient) PublishData(request model.PublishDataRequest) error {
 	if err != nil {
 		return fmt.Errorf("marshal request failed due to error: %v", err)
 	}
-	r := bytes.NewReader(bs)
-	url := fmt.Sprint(t.trackerAddress, publishDataRoute)
-	req, err := http.NewRequest("POST", url, r)
-	if err != nil {
-		return fmt.Errorf("creating publish data request failed due to error:%v", err)
-	}
-
-	resp, err := t.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("publish data request failed due to error: %v", err)
-	}
-	if resp.StatusCode == 201 {
-		fmt.Println("New data published successfully...")
-	} else {
-		fmt.Println("Failed to publish new data.", resp.Status)
-	}
+	// sequence is taken from the same tracker, unless it fails meanwhile
+	return t.trackers.Do(request.RootHash.Publisher, func(address string) error {
+		url := fmt.Sprint(address, publishDataRoute)
+		req, err := http.NewRequest("POST", url, bytes.NewReader(bs))
+		if err != nil {
+			return fmt.Errorf("creating publish data request failed due to error:%v", err)
+		}
 
-	return nil
+		resp, err := t.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("publish data request failed due to error: %w", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != 201 {
+			return tracker.NewStatusError("publish data request", resp)
+		}
+		t.logger.WithFields(log.Fields{
+			logging.TrackerField:   address,
+			logging.PublisherField: request.RootHash.Publisher,
+			logging.SequenceField:  request.RootHash.Sequence,
+		}).Debug("New data published successfully")
+		return nil
+	})
 }
 
 func (t TrackerClient) GetNewSequenceNumber(publicKey string) (uint64, error) {
 	maxSeq := uint64(0)
-	url := fmt.Sprint(t.trackerAddress, nextSequenceRoute, publicKey)
+	err := t.trackers.Do(publicKey, func(address string) error {
+		var err error
+		maxSeq, err = t.getNewSequenceNumber(address, publicKey)
+		return err
+	})
+	return maxSeq, err
+}
+
+func (t TrackerClient) getNewSequenceNumber(address, publicKey string) (uint64, error) {
+	maxSeq := uint64(0)
+	url := fmt.Sprint(address, nextSequenceRoute, publicKey)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return maxSeq, fmt.Errorf("error creating next sequence request")
@@ -91,24 +131,26 @@ func (t TrackerClient) GetNewSequenceNumber(publicKey string) (uint64, error) {
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return maxSeq, fmt.Errorf("get next sequence request failed due to error: %v", err)
+		return maxSeq, fmt.Errorf("get next sequence request failed due to error: %w", err)
 	}
+	defer resp.Body.Close()
 
+	logger := t.logger.WithFields(log.Fields{logging.TrackerField: address, logging.PublisherField: publicKey})
 	if resp.StatusCode != 200 {
 		if resp.StatusCode == 404 {
-			fmt.Println("Have not found records for the given pub key, returning 1")
+			logger.Debug("Tracker has no sequence of the publisher, next sequence is 1")
 			maxSeq++
 			return maxSeq, nil
 		}
-		return maxSeq, fmt.Errorf("get next sequence request returned non 200 status code %d", resp.StatusCode)
+		return maxSeq, tracker.NewStatusError("get next sequence request", resp)
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return maxSeq, fmt.Errorf("get next sequence reading body failed due to error: %s", err)
+		return maxSeq, fmt.Errorf("get next sequence reading body failed due to error: %w", err)
 	}
 	maxSeq = binary.BigEndian.Uint64(body)
 
-	fmt.Println("next sequence number is ", maxSeq)
+	logger.WithField(logging.SequenceField, maxSeq).Debug("Received next sequence")
 	return maxSeq, nil
 }
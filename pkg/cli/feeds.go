@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/SkycoinProject/cxo-2/pkg/cli/client"
+	"github.com/spf13/cobra"
+)
+
+func feedsCmd(client *client.NodeClient) *cobra.Command {
+	feedsCmd := &cobra.Command{
+		Short:                 "List feeds stored by the node",
+		Use:                   "feeds",
+		Long:                  "List state, latest stored sequence and its size of every feed known to the local node",
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			feeds, err := client.Feeds()
+			if err != nil {
+				return err
+			}
+
+			for _, feed := range feeds {
+				fmt.Printf("%s  %-10s  sequence: %v  size: %v\n", feed.Publisher, feed.State, feed.Sequence, feed.Size)
+				if feed.Reason != "" {
+					fmt.Println("    ", feed.Reason)
+				}
+			}
+			return nil
+		},
+	}
+
+	return feedsCmd
+}
+
+func resumeCmd(client *client.NodeClient) *cobra.Command {
+	resumeCmd := &cobra.Command{
+		Short:                 "Resume feed refused for exceeding quota",
+		Use:                   "resume [public_key]",
+		Long:                  "Make feed that was paused or refused for exceeding quota active again and retrieve its latest refused sequence",
+		SilenceUsage:          true,
+		Args:                  cobra.MinimumNArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			pubKey := args[0]
+			if pubKey == "" {
+				return c.Help()
+			}
+
+			if err := client.Resume(pubKey); err != nil {
+				return err
+			}
+
+			fmt.Println("Feed resumed: ", pubKey)
+			return nil
+		},
+	}
+
+	return resumeCmd
+}
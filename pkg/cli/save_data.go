@@ -5,11 +5,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/SkycoinProject/cxo-2/pkg/cli/client"
 	"github.com/SkycoinProject/cxo-2/pkg/config"
 	"github.com/SkycoinProject/cxo-2/pkg/model"
-	dmsgcipher "github.com/SkycoinProject/dmsg/cipher"
+	"github.com/SkycoinProject/cxo-2/pkg/signature"
 	"github.com/spf13/cobra"
 )
 
@@ -46,14 +47,21 @@ func publishDataCmd(client *client.TrackerClient, config config.Config) *cobra.C
 				return err
 			}
 
-			sig, err := signParcel(req.Parcel, config.PubKey, config.SecKey)
-			if err != nil {
-				fmt.Println("Signing parcel failed due to error ", err)
-				return err
+			if req.RootHash.ObjectHeaderHash == "" {
+				return fmt.Errorf("root hash in request file doesn't reference object header")
+			}
+			if req.RootHash.Timestamp.IsZero() {
+				req.RootHash.Timestamp = time.Now()
 			}
-
 			req.RootHash.Publisher = config.PubKey.Hex()
 			req.RootHash.Sequence = seqNo
+			req.RootHash.SignatureVersion = signature.RootHashVersion
+
+			sig, err := signature.SignRootHash(req.RootHash, config.PubKey, config.SecKey)
+			if err != nil {
+				fmt.Println("Signing root hash failed due to error ", err)
+				return err
+			}
 			req.RootHash.Signature = sig
 
 			reqBytes, err := json.MarshalIndent(req, "", "  ")
@@ -77,20 +85,3 @@ func publishDataCmd(client *client.TrackerClient, config config.Config) *cobra.C
 
 	return publishDataCmd
 }
-
-func signParcel(parcel model.Parcel, pubKey dmsgcipher.PubKey, secKey dmsgcipher.SecKey) (string, error) {
-	parcelBytes, err := json.Marshal(parcel)
-	if err != nil {
-		return "", fmt.Errorf("marshal parcel faled due to err: %v", err)
-	}
-	signature, err := dmsgcipher.SignPayload(parcelBytes, secKey)
-	if err != nil {
-		return "", fmt.Errorf("signing parcel faled due to err: %v", err)
-	}
-
-	if err = dmsgcipher.VerifyPubKeySignedPayload(pubKey, signature, parcelBytes); err != nil {
-		return "", fmt.Errorf("parcel signature verification failed due to error: %v", err)
-	}
-
-	return signature.Hex(), nil
-}
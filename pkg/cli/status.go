@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/SkycoinProject/cxo-2/pkg/cli/client"
+	"github.com/spf13/cobra"
+)
+
+func statusCmd(client *client.NodeClient) *cobra.Command {
+	statusCmd := &cobra.Command{
+		Short:                 "Show status of the node",
+		Use:                   "status",
+		Long:                  "Show readiness of the local node, health of its trackers, stored feeds with their latest sequence, unfinished downloads and retrieval queue",
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			status, err := client.Status()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Public key: ", status.PubKey)
+			fmt.Printf("Address:     %v (%v)\n", status.Address, status.Transport)
+			fmt.Println("Status:     ", status.Health.Status)
+			checks := make([]string, 0, len(status.Health.Checks))
+			for check := range status.Health.Checks {
+				checks = append(checks, check)
+			}
+			sort.Strings(checks)
+			for _, check := range checks {
+				fmt.Printf("    %-10s %v\n", check, status.Health.Checks[check])
+			}
+
+			fmt.Println("Trackers:")
+			for _, tracker := range status.Trackers {
+				health := "healthy"
+				if !tracker.Healthy {
+					health = "unhealthy"
+				}
+				checkedAt := "never"
+				if !tracker.CheckedAt.IsZero() {
+					checkedAt = tracker.CheckedAt.Format("2006-01-02 15:04:05")
+				}
+				fmt.Printf("    %s  priority: %v  %-9s  failures: %v  checked: %v\n", tracker.Address,
+					tracker.Priority, health, tracker.Failures, checkedAt)
+			}
+
+			fmt.Println("Feeds:")
+			for _, feed := range status.Feeds {
+				fmt.Printf("    %s  %-10s  sequence: %v  size: %v\n", feed.Publisher, feed.State, feed.Sequence, feed.Size)
+			}
+
+			fmt.Println("Downloads:")
+			for _, download := range status.Downloads {
+				fmt.Printf("    %s  %-9s  size: %v\n", download.RootHash.Key(), download.Status, download.Size)
+			}
+			fmt.Printf("Queue:      running: %v  waiting: %v\n", status.Queue.Running, status.Queue.Waiting)
+			return nil
+		},
+	}
+
+	return statusCmd
+}
@@ -7,11 +7,11 @@ import (
 	"github.com/spf13/cobra"
 )
 
-func subscribeCmd(client *client.TrackerClient) *cobra.Command {
+func subscribeCmd(client *client.TrackerClient, nodeClient *client.NodeClient) *cobra.Command {
 	subscribeCmd := &cobra.Command{
 		Short:                 "Subscribe to public key",
 		Use:                   "subscribe [flags] [public_key]",
-		Long:                  "Subscribe to public key on CXO Tracker service",
+		Long:                  "Subscribe to public key on the local node and on CXO Tracker service",
 		SilenceUsage:          true,
 		Args:                  cobra.MinimumNArgs(1),
 		DisableFlagsInUseLine: true,
@@ -21,6 +21,12 @@ func subscribeCmd(client *client.TrackerClient) *cobra.Command {
 				return c.Help()
 			}
 
+			// node refuses root hashes of publishers it isn't subscribed to, so it has to accept them before
+			// tracker starts sending them
+			if err := nodeClient.Subscribe(pubKey); err != nil {
+				return err
+			}
+
 			err := client.Subscribe(pubKey)
 
 			switch err.(type) {
@@ -35,3 +41,68 @@ func subscribeCmd(client *client.TrackerClient) *cobra.Command {
 
 	return subscribeCmd
 }
+
+func unsubscribeCmd(client *client.NodeClient) *cobra.Command {
+	unsubscribeCmd := &cobra.Command{
+		Short:                 "Unsubscribe from public key",
+		Use:                   "unsubscribe [public_key]",
+		Long:                  "Make the local node refuse new root hashes of the publisher, data already stored is kept",
+		SilenceUsage:          true,
+		Args:                  cobra.MinimumNArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			pubKey := args[0]
+			if pubKey == "" {
+				return c.Help()
+			}
+
+			if err := client.Unsubscribe(pubKey); err != nil {
+				return err
+			}
+
+			fmt.Println("Unsubscribed from: ", pubKey)
+			return nil
+		},
+	}
+
+	return unsubscribeCmd
+}
+
+func subscriptionsCmd(client *client.NodeClient) *cobra.Command {
+	subscriptionsCmd := &cobra.Command{
+		Short:                 "List subscribed and blocked public keys",
+		Use:                   "subscriptions",
+		Long:                  "List publishers whose root hashes are accepted and ones that are blocked by the local node",
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			subscriptions, err := client.Subscriptions()
+			if err != nil {
+				return err
+			}
+			blocked, err := client.BlockedPublishers()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Subscribed:")
+			for _, subscription := range subscriptions {
+				fmt.Println("    ", subscription.Publisher, configuredNote(subscription.Configured))
+			}
+			fmt.Println("Blocked:")
+			for _, publisher := range blocked {
+				fmt.Println("    ", publisher.Publisher, configuredNote(publisher.Configured))
+			}
+			return nil
+		},
+	}
+
+	return subscriptionsCmd
+}
+
+func configuredNote(configured bool) string {
+	if configured {
+		return "(config file)"
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"unicode"
+
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/parcel"
+	"github.com/spf13/cobra"
+)
+
+func verifyCmd() *cobra.Command {
+	var legacy bool
+	verifyCmd := &cobra.Command{
+		Short:                 "Verify publish data request or exported feed",
+		Use:                   "verify [flags] [path_to_file]",
+		Long:                  "Verify hashes, references, size fields and signature of publish data request JSON or feed archive, without connecting to node or tracker",
+		SilenceUsage:          true,
+		Args:                  cobra.MinimumNArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			filePath := args[0]
+			if filePath == "" {
+				return c.Help()
+			}
+
+			f, err := os.Open(filePath)
+			if err != nil {
+				return fmt.Errorf("could not read file: %v", err)
+			}
+			defer f.Close()
+
+			feed, problems, err := readFeed(bufio.NewReader(f))
+			if err != nil {
+				return err
+			}
+			problems = append(problems, parcel.Verify(feed, legacy)...)
+
+			for _, problem := range problems {
+				fmt.Println(problem)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("found %v problems", len(problems))
+			}
+
+			fmt.Printf("Root hash with key: %v is valid, %v object headers and %v objects verified\n",
+				feed.RootHash.Key(), len(feed.ObjectHeaders), len(feed.Objects))
+			return nil
+		},
+	}
+	verifyCmd.Flags().BoolVar(&legacy, "legacy", false,
+		"accept legacy signature of the whole parcel, which doesn't cover sequence and timestamp")
+
+	return verifyCmd
+}
+
+// readFeed - load feed from publish data request JSON, or from archive created by export command
+func readFeed(r *bufio.Reader) (parcel.Feed, []error, error) {
+	if isJSON(r) {
+		var req model.PublishDataRequest
+		if err := json.NewDecoder(r).Decode(&req); err != nil {
+			return parcel.Feed{}, nil, fmt.Errorf("could not unmarshal publish data request: %v", err)
+		}
+		feed, err := parcel.NewFeed(req)
+		return feed, nil, err
+	}
+
+	feed, problems, err := parcel.ReadArchive(r)
+	if err != nil {
+		return parcel.Feed{}, nil, fmt.Errorf("could not read archive: %v", err)
+	}
+	return feed, problems, nil
+}
+
+// isJSON - whether the first non-space character is the start of JSON object, nothing is consumed from reader
+func isJSON(r *bufio.Reader) bool {
+	for n := 1; ; n++ {
+		b, _ := r.Peek(n)
+		if len(b) < n {
+			return false
+		}
+		if c := rune(b[n-1]); !unicode.IsSpace(c) {
+			return c == '{'
+		}
+	}
+}
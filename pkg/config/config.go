@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 
@@ -10,18 +11,140 @@ import (
 	"github.com/SkycoinProject/dmsg/disc"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
 	yaml "gopkg.in/yaml.v2"
 )
 
 // Config - node's configuration model
 type Config struct {
-	TrackerAddress string
-	PubKey         cipher.PubKey
-	SecKey         cipher.SecKey
-	Port           uint16
-	Discovery      disc.APIClient
+	Trackers   []TrackerConfig
+	PubKey     cipher.PubKey
+	SecKey     cipher.SecKey
+	Port       uint16
+	Server     ServerConfig
+	APIAddress string
+	Discovery  disc.APIClient
+	Storage    StorageConfig
+	Quota      QuotaConfig
+	Trust      TrustConfig
+	P2P        P2PConfig
+	Seeding    SeedingConfig
+	Log        LogConfig
 }
 
+// TrackerConfig - tracker node retrieves data from, trackers with lower priority are used first. PubKey is set
+// only for trackers reached over dmsg.
+type TrackerConfig struct {
+	Address  string
+	PubKey   cipher.PubKey
+	Priority int
+}
+
+// ServerConfig - how node serves its routes to trackers and other nodes. Port is used with dmsg transport, Address
+// is both listened on and announced to trackers with HTTP transport.
+type ServerConfig struct {
+	Transport string
+	Address   string
+}
+
+// Transports node can use, also used as schemes of tracker URLs
+const (
+	DMSGTransport  = "dmsg"
+	HTTPTransport  = "http"
+	HTTPSTransport = "https"
+)
+
+// StorageConfig - selects where node keeps retrieved data
+type StorageConfig struct {
+	Backend      string
+	DatabasePath string
+	ObjectsPath  string
+}
+
+// QuotaConfig - limits of data stored by the node, in bytes. Zero means no limit.
+type QuotaConfig struct {
+	FeedBytes  uint64
+	TotalBytes uint64
+	// Feeds - limits of single publishers, overriding FeedBytes
+	Feeds  map[string]uint64
+	Policy string
+}
+
+// FeedLimit - quota of the publisher's feed
+func (q QuotaConfig) FeedLimit(publisher string) uint64 {
+	if limit, ok := q.Feeds[publisher]; ok {
+		return limit
+	}
+	return q.FeedBytes
+}
+
+// TrustConfig - publishers listed in config file, in addition to ones subscribed and blocked through the CLI,
+// and trackers allowed to notify the node about new root hashes, by dmsg public key or by host if they use HTTP
+type TrustConfig struct {
+	Subscriptions     []string
+	BlockedPublishers []string
+	Trackers          []cipher.PubKey
+	TrackerHosts      []string
+	// LegacySignatures - accept root hashes signed with the whole parcel by publishers that never signed root hash
+	LegacySignatures bool
+}
+
+// P2PConfig - exchange of object headers and objects directly between nodes
+type P2PConfig struct {
+	Enabled bool
+	// Peers - dmsg addresses of nodes asked for data of every feed, in addition to ones found through trackers
+	Peers []string
+}
+
+// SeedingConfig - feeds node serves to other nodes and bandwidth it can use for that. Zero means no limit.
+type SeedingConfig struct {
+	// Feeds - publishers whose data is served, "*" serves every feed
+	Feeds              []string
+	BytesPerSecond     uint64
+	FeedBytesPerSecond uint64
+}
+
+// Enabled - whether node serves data of any feed
+func (s SeedingConfig) Enabled() bool {
+	return len(s.Feeds) > 0
+}
+
+// Seeds - whether node serves data of the publisher's feed
+func (s SeedingConfig) Seeds(publisher string) bool {
+	for _, feed := range s.Feeds {
+		if feed == publisher || feed == AllFeeds {
+			return true
+		}
+	}
+	return false
+}
+
+// LogConfig - minimal level of logged entries and whether they are written as JSON instead of text
+type LogConfig struct {
+	Level log.Level
+	JSON  bool
+}
+
+// AllFeeds - seeding feed entry matching every publisher
+const AllFeeds = "*"
+
+// Policies applied to feeds whose new sequence exceeds quota
+const (
+	// RejectPolicy - refuse the sequence and remove stored data of the feed
+	RejectPolicy = "reject"
+	// KeepPreviousPolicy - refuse the sequence and keep the previous one
+	KeepPreviousPolicy = "keep-previous"
+	// PausePolicy - refuse the sequence and stop retrieving the feed until it's resumed
+	PausePolicy = "pause"
+)
+
+// Storage backends supported by the node
+const (
+	BoltStorage       = "bolt"
+	FilesystemStorage = "filesystem"
+	MemoryStorage     = "memory"
+)
+
 const (
 	appRootFolderName   = ".cxo-node"
 	keysFileName        = "keys.txt"
@@ -29,6 +152,10 @@ const (
 	defaultDiscoveryURL = "http://dmsg.discovery.skywire.cc" //"http://localhost:9090"
 	defaultTrackerURL   = "dmsg://036cbf1297c2433303909674e1bc25ce341ec1c16012ba28a265066847960e2514:8084"
 	serverPort          = uint16(8083)
+	serverAddress       = "127.0.0.1:8083"
+	apiAddress          = "127.0.0.1:6421"
+	databaseFileName    = "cxo-node.db"
+	objectsFolderName   = "objects"
 )
 
 // LoadConfig - load node's configuration
@@ -60,28 +187,207 @@ func LoadConfig(local *bool) Config {
 	sPK, sSK := util.PrepareKeyPair(keysFilePath)
 
 	configFilePath := filepath.Join(appRootFolderPath, configFileName)
+	databasePath := filepath.Join(appRootFolderPath, databaseFileName)
 	confFile := configFile{
-		TrackerURL:   defaultTrackerURL,
-		DiscoveryURL: defaultDiscoveryURL,
+		TrackerURL:      defaultTrackerURL,
+		DiscoveryURL:    defaultDiscoveryURL,
+		StorageBackend:  FilesystemStorage,
+		DatabasePath:    databasePath,
+		ObjectsPath:     filepath.Join(appRootFolderPath, objectsFolderName),
+		QuotaPolicy:     KeepPreviousPolicy,
+		ServerTransport: DMSGTransport,
+		ServerAddress:   serverAddress,
+		APIAddress:      apiAddress,
+		LogLevel:        log.InfoLevel.String(),
 	}
 	readConfigFile(configFilePath, &confFile)
 	readEnv(&confFile)
+	if confFile.DatabasePath == databasePath {
+		confFile.DatabasePath = moveLegacyDatabase(databasePath)
+	}
 	//TODO consider validation of TrackerURL and DiscoveryURL
 
+	switch confFile.StorageBackend {
+	case BoltStorage, FilesystemStorage, MemoryStorage:
+	default:
+		processError("invalid storage backend", fmt.Errorf("%q is not one of: %v, %v, %v",
+			confFile.StorageBackend, BoltStorage, FilesystemStorage, MemoryStorage))
+	}
+	switch confFile.ServerTransport {
+	case DMSGTransport, HTTPTransport:
+	default:
+		processError("invalid server transport", fmt.Errorf("%q is not one of: %v, %v",
+			confFile.ServerTransport, DMSGTransport, HTTPTransport))
+	}
+	switch confFile.QuotaPolicy {
+	case RejectPolicy, KeepPreviousPolicy, PausePolicy:
+	default:
+		processError("invalid quota policy", fmt.Errorf("%q is not one of: %v, %v, %v",
+			confFile.QuotaPolicy, RejectPolicy, KeepPreviousPolicy, PausePolicy))
+	}
+	logLevel, err := log.ParseLevel(confFile.LogLevel)
+	if err != nil {
+		processError("invalid log level", err)
+	}
+	for _, key := range append(confFile.Subscriptions, confFile.BlockedPublishers...) {
+		var pubKey cipher.PubKey
+		if err := pubKey.UnmarshalText([]byte(key)); err != nil {
+			processError("invalid publisher public key", fmt.Errorf("%q: %v", key, err))
+		}
+	}
+	trackers, err := trackerConfigs(confFile)
+	if err != nil {
+		processError("invalid tracker", err)
+	}
+	trustedTrackers, err := trustedTrackers(trackers, confFile.TrustedTrackers)
+	if err != nil {
+		processError("invalid trusted tracker", err)
+	}
+	var trackerHosts []string
+	for _, tracker := range trackers {
+		if tracker.PubKey.Null() {
+			u, _ := url.Parse(tracker.Address)
+			trackerHosts = append(trackerHosts, u.Hostname())
+		}
+	}
+
 	return Config{
-		TrackerAddress: confFile.TrackerURL,
-		PubKey:         sPK,
-		SecKey:         sSK,
-		Port:           serverPort,
-		Discovery:      disc.NewHTTP(confFile.DiscoveryURL),
+		Trackers: trackers,
+		PubKey:   sPK,
+		SecKey:   sSK,
+		Port:     serverPort,
+		Server: ServerConfig{
+			Transport: confFile.ServerTransport,
+			Address:   confFile.ServerAddress,
+		},
+		APIAddress: confFile.APIAddress,
+		Discovery:  disc.NewHTTP(confFile.DiscoveryURL),
+		Storage: StorageConfig{
+			Backend:      confFile.StorageBackend,
+			DatabasePath: confFile.DatabasePath,
+			ObjectsPath:  confFile.ObjectsPath,
+		},
+		Quota: QuotaConfig{
+			FeedBytes:  confFile.FeedQuota,
+			TotalBytes: confFile.TotalQuota,
+			Feeds:      confFile.FeedQuotas,
+			Policy:     confFile.QuotaPolicy,
+		},
+		Trust: TrustConfig{
+			Subscriptions:     confFile.Subscriptions,
+			BlockedPublishers: confFile.BlockedPublishers,
+			Trackers:          trustedTrackers,
+			TrackerHosts:      trackerHosts,
+			LegacySignatures:  confFile.LegacySignatures,
+		},
+		P2P: P2PConfig{
+			Enabled: confFile.P2P,
+			Peers:   confFile.Peers,
+		},
+		Seeding: SeedingConfig{
+			Feeds:              confFile.SeedFeeds,
+			BytesPerSecond:     confFile.SeedBandwidth,
+			FeedBytesPerSecond: confFile.SeedFeedBandwidth,
+		},
+		Log: LogConfig{
+			Level: logLevel,
+			JSON:  confFile.LogJSON,
+		},
 	}
 }
 
+// trackerConfigs - trackers listed in config file, or ones from env variable ordered by priority, or the single
+// tracker from trackerUrl
+func trackerConfigs(confFile configFile) ([]TrackerConfig, error) {
+	entries := confFile.Trackers
+	if len(entries) == 0 {
+		urls := confFile.TrackerURLs
+		if len(urls) == 0 {
+			urls = []string{confFile.TrackerURL}
+		}
+		for i, url := range urls {
+			entries = append(entries, trackerEntry{URL: url, Priority: i})
+		}
+	}
+
+	trackers := make([]TrackerConfig, 0, len(entries))
+	for _, entry := range entries {
+		u, err := url.Parse(entry.URL)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not valid URL: %v", entry.URL, err)
+		}
+		tracker := TrackerConfig{Address: entry.URL, Priority: entry.Priority}
+		switch u.Scheme {
+		case DMSGTransport:
+			if tracker.PubKey, err = dmsgPubKey(u); err != nil {
+				return nil, err
+			}
+		case HTTPTransport, HTTPSTransport:
+		default:
+			return nil, fmt.Errorf("%q has scheme that is not one of: %v, %v, %v", entry.URL,
+				DMSGTransport, HTTPTransport, HTTPSTransport)
+		}
+		trackers = append(trackers, tracker)
+	}
+	return trackers, nil
+}
+
+// trustedTrackers - public keys of trackers node retrieves data from, followed by other trusted trackers
+func trustedTrackers(trackers []TrackerConfig, keys []string) ([]cipher.PubKey, error) {
+	trusted := make([]cipher.PubKey, 0, len(trackers)+len(keys))
+	for _, tracker := range trackers {
+		if !tracker.PubKey.Null() {
+			trusted = append(trusted, tracker.PubKey)
+		}
+	}
+	for _, key := range keys {
+		var pubKey cipher.PubKey
+		if err := pubKey.UnmarshalText([]byte(key)); err != nil {
+			return nil, fmt.Errorf("%q: %v", key, err)
+		}
+		trusted = append(trusted, pubKey)
+	}
+	return trusted, nil
+}
+
+// dmsgPubKey - public key from dmsg URL, e.g. dmsg://<public key>:<port>
+func dmsgPubKey(u *url.URL) (cipher.PubKey, error) {
+	var pubKey cipher.PubKey
+	if err := pubKey.UnmarshalText([]byte(u.Hostname())); err != nil {
+		return pubKey, fmt.Errorf("%q doesn't contain dmsg public key: %v", u.String(), err)
+	}
+	return pubKey, nil
+}
+
+// moveLegacyDatabase - older nodes kept database in working directory, it's moved to the app root folder if
+// database doesn't exist there yet, so upgraded node keeps its data. Database is used in place if it can't be moved.
+func moveLegacyDatabase(path string) string {
+	legacyPath, err := filepath.Abs(databaseFileName)
+	if err != nil || legacyPath == path {
+		return path
+	}
+	if _, err := os.Stat(legacyPath); err != nil {
+		return path
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		log.WithField("path", legacyPath).Warnf("Database of older node version is ignored, since %v already exists", path)
+		return path
+	}
+
+	if err := os.Rename(legacyPath, path); err != nil {
+		log.WithField("path", legacyPath).WithError(err).
+			Warnf("Moving database of older node version to %v failed, it's used in place", path)
+		return legacyPath
+	}
+	log.WithField("path", legacyPath).Warnf("Database of older node version is moved to %v", path)
+	return path
+}
+
 func readConfigFile(path string, conf *configFile) {
 	f, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			fmt.Println("File containing config doesn't exist. Reading config from env variables...")
+			log.Info("File containing config doesn't exist. Reading config from env variables...")
 			return
 		}
 		processError("unable to open config file", err)
@@ -102,12 +408,40 @@ func readEnv(cfg *configFile) {
 }
 
 func processError(message string, err error) {
-	fmt.Println(message)
-	fmt.Println(err)
+	fmt.Fprintln(os.Stderr, message)
+	fmt.Fprintln(os.Stderr, err)
 	os.Exit(2)
 }
 
 type configFile struct {
-	TrackerURL   string `envconfig:"TRACKER_URL" yaml:"trackerUrl"`
-	DiscoveryURL string `envconfig:"DISCOVERY_URL" yaml:"discoveryUrl"`
+	TrackerURL        string            `envconfig:"TRACKER_URL" yaml:"trackerUrl"`
+	TrackerURLs       []string          `envconfig:"TRACKER_URLS" yaml:"-"`
+	Trackers          []trackerEntry    `ignored:"true" yaml:"trackers"`
+	DiscoveryURL      string            `envconfig:"DISCOVERY_URL" yaml:"discoveryUrl"`
+	StorageBackend    string            `envconfig:"STORAGE_BACKEND" yaml:"storageBackend"`
+	DatabasePath      string            `envconfig:"DATABASE_PATH" yaml:"databasePath"`
+	ObjectsPath       string            `envconfig:"OBJECTS_PATH" yaml:"objectsPath"`
+	FeedQuota         uint64            `envconfig:"FEED_QUOTA" yaml:"feedQuota"`
+	TotalQuota        uint64            `envconfig:"TOTAL_QUOTA" yaml:"totalQuota"`
+	FeedQuotas        map[string]uint64 `envconfig:"FEED_QUOTAS" yaml:"feedQuotas"`
+	QuotaPolicy       string            `envconfig:"QUOTA_POLICY" yaml:"quotaPolicy"`
+	Subscriptions     []string          `envconfig:"SUBSCRIPTIONS" yaml:"subscriptions"`
+	BlockedPublishers []string          `envconfig:"BLOCKED_PUBLISHERS" yaml:"blockedPublishers"`
+	TrustedTrackers   []string          `envconfig:"TRUSTED_TRACKERS" yaml:"trustedTrackers"`
+	LegacySignatures  bool              `envconfig:"LEGACY_SIGNATURES" yaml:"legacySignatures"`
+	P2P               bool              `envconfig:"P2P" yaml:"p2p"`
+	Peers             []string          `envconfig:"PEERS" yaml:"peers"`
+	ServerTransport   string            `envconfig:"SERVER_TRANSPORT" yaml:"serverTransport"`
+	ServerAddress     string            `envconfig:"SERVER_ADDRESS" yaml:"serverAddress"`
+	APIAddress        string            `envconfig:"API_ADDRESS" yaml:"apiAddress"`
+	SeedFeeds         []string          `envconfig:"SEED_FEEDS" yaml:"seedFeeds"`
+	SeedBandwidth     uint64            `envconfig:"SEED_BANDWIDTH" yaml:"seedBandwidth"`
+	SeedFeedBandwidth uint64            `envconfig:"SEED_FEED_BANDWIDTH" yaml:"seedFeedBandwidth"`
+	LogLevel          string            `envconfig:"LOG_LEVEL" yaml:"logLevel"`
+	LogJSON           bool              `envconfig:"LOG_JSON" yaml:"logJson"`
+}
+
+type trackerEntry struct {
+	URL      string `yaml:"url"`
+	Priority int    `yaml:"priority"`
 }
@@ -6,5 +6,23 @@ var (
 	ErrCannotFindObjectHeader = errors.New("cannot find object header by hash")
 	ErrCannotFindObject       = errors.New("cannot find object by hash")
 	ErrCannotFindRootHash     = errors.New("cannot find root hash by key")
+	ErrCannotFindDownload     = errors.New("cannot find download by root hash key")
 	ErrUnableToProcessRequest = errors.New("unable to process fields from the request")
+	ErrStaleSequence          = errors.New("root hash sequence is lower than the latest stored sequence of the feed")
+	ErrForkedSequence         = errors.New("root hash differs from the stored root hash with the same sequence")
+	ErrTimestampInFuture      = errors.New("root hash timestamp is too far in the future")
+	ErrCannotFindFeed         = errors.New("cannot find feed by publisher")
+	ErrQuotaExceeded          = errors.New("root hash data exceeds storage quota")
+	ErrFeedPaused             = errors.New("feed is paused")
+	ErrFeedEvicted            = errors.New("feed is evicted")
+	ErrLegacySignature        = errors.New("legacy signature doesn't cover sequence and timestamp of root hash")
+	ErrDeclaredSizeExceeded   = errors.New("retrieved data exceeds size declared by root object header")
+	ErrCannotFindSubscription = errors.New("cannot find subscription by publisher")
+	ErrCannotFindBlocked      = errors.New("cannot find blocked publisher")
+	ErrNotSubscribed          = errors.New("publisher is not subscribed")
+	ErrPublisherBlocked       = errors.New("publisher is blocked")
+	ErrInvalidPublicKey       = errors.New("invalid public key")
+	ErrUntrustedTracker       = errors.New("root hash is not sent by trusted tracker")
+	ErrConfiguredPublisher    = errors.New("publisher is listed in config file and can be changed only there")
+	ErrDownloadInterrupted    = errors.New("download is interrupted by node shutdown")
 )
@@ -0,0 +1,35 @@
+// Package logging - loggers of node and CLI configured from node's configuration
+package logging
+
+import (
+	"os"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Field names used by structured log entries, so entries of the same feed can be filtered across components
+const (
+	PublisherField = "publisher"
+	SequenceField  = "sequence"
+	HashField      = "hash"
+	TrackerField   = "tracker"
+)
+
+// New - logger writing entries of configured level and above to stderr
+func New(cfg config.LogConfig) *log.Logger {
+	logger := log.New()
+	Configure(logger, cfg)
+	return logger
+}
+
+// Configure - apply configuration to existing logger, e.g. to the standard one that dependencies log to
+func Configure(logger *log.Logger, cfg config.LogConfig) {
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(cfg.Level)
+	if cfg.JSON {
+		logger.SetFormatter(&log.JSONFormatter{})
+	} else {
+		logger.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	}
+}
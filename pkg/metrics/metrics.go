@@ -0,0 +1,266 @@
+// Package metrics - counters, gauges and histograms exposed in Prometheus text exposition format, so that node can
+// be scraped by Prometheus without depending on its client library
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType - content type of Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets - histogram buckets suitable for durations of network requests in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+// Registry - metrics written together in order they were registered
+type Registry struct {
+	mux     sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// NewRegistry - create empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Counter - register counter with given name, help and names of its labels
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{family: newFamily(name, help, labels)}
+	r.register(c)
+	return c
+}
+
+// GaugeFunc - register gauge whose value is computed by the function whenever metrics are written
+func (r *Registry) GaugeFunc(name, help string, value func() float64) {
+	r.register(&gaugeFunc{family: newFamily(name, help, nil), value: value})
+}
+
+// Histogram - register histogram with given name, help, upper bounds of its buckets and names of its labels
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+	h := &Histogram{family: newFamily(name, help, labels), buckets: bounds}
+	r.register(h)
+	return h
+}
+
+func (r *Registry) register(m metric) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Write - write all metrics in Prometheus text exposition format
+func (r *Registry) Write(w io.Writer) error {
+	r.mux.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mux.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP - serve metrics to Prometheus scraper
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_ = r.Write(w)
+}
+
+// family - name, help and label names shared by all series of a metric
+type family struct {
+	name   string
+	help   string
+	labels []string
+	mux    sync.Mutex
+}
+
+func newFamily(name, help string, labels []string) family {
+	return family{name: name, help: help, labels: labels}
+}
+
+func (f *family) writeHeader(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, helpEscaper.Replace(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, kind)
+}
+
+// key - identify series by its label values, panics if their number doesn't match label names, same as
+// Prometheus client does
+func (f *family) key(labelValues []string) string {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metric %v expects %v label values but received %v", f.name, len(f.labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+// labelPairs - label names and values formatted for the series, with extra pair appended if given
+func (f *family) labelPairs(labelValues []string, extra ...string) string {
+	pairs := make([]string, 0, len(labelValues)+1)
+	for i, value := range labelValues {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, f.labels[i], labelEscaper.Replace(value)))
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[0], labelEscaper.Replace(extra[1])))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter - value that only increases, e.g. number of requests
+type Counter struct {
+	family
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labelValues []string
+	value       float64
+}
+
+// Inc - increase counter with given label values by 1
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add - increase counter with given label values, negative values are ignored
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	key := c.key(labelValues)
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.series == nil {
+		c.series = make(map[string]*counterSeries)
+	}
+	series, ok := c.series[key]
+	if !ok {
+		series = &counterSeries{labelValues: append([]string(nil), labelValues...)}
+		c.series[key] = series
+	}
+	series.value += value
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.writeHeader(w, "counter")
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	// counter without labels is exposed from the start, so rate can be computed from its first increase
+	if len(c.labels) == 0 && len(c.series) == 0 {
+		fmt.Fprintf(w, "%s 0\n", c.name)
+	}
+	keys := make([]string, 0, len(c.series))
+	for key := range c.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		series := c.series[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(series.labelValues), formatFloat(series.value))
+	}
+}
+
+// gaugeFunc - value that can go up and down, computed when metrics are written
+type gaugeFunc struct {
+	family
+	value func() float64
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	g.writeHeader(w, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.value()))
+}
+
+// Histogram - observed values counted in buckets, e.g. request durations
+type Histogram struct {
+	family
+	buckets []float64
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+// Observe - add value to histogram with given label values
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if h.series == nil {
+		h.series = make(map[string]*histogramSeries)
+	}
+	series, ok := h.series[key]
+	if !ok {
+		series = &histogramSeries{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = series
+	}
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		series.counts[i]++
+	}
+	series.count++
+	series.sum += value
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.writeHeader(w, "histogram")
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		series := h.series[key]
+		// buckets are cumulative in exposition format
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += series.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(series.labelValues, "le", formatFloat(bound)),
+				cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(series.labelValues, "le", "+Inf"), series.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(series.labelValues), formatFloat(series.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(series.labelValues), series.count)
+	}
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
@@ -9,6 +9,7 @@ import (
 type RootHash struct {
 	Publisher        string    `json:"publisher"`
 	Signature        string    `json:"signature"`
+	SignatureVersion uint8     `json:"signatureVersion"`
 	Sequence         uint64    `json:"sequence"`
 	Timestamp        time.Time `json:"timestamp"`
 	ObjectHeaderHash string    `json:"objectHeaderHash"`
@@ -60,6 +61,12 @@ type GetObjectHeadersResponse struct {
 	ObjectHeaders []ObjectHeader `json:"objectHeaders"`
 }
 
+// GetPeersResponse - nodes known to tracker to be subscribed to the feed, as dmsg addresses <public key>:<port>
+// or URLs with scheme of their transport
+type GetPeersResponse struct {
+	Peers []string `json:"peers"`
+}
+
 type RegisterAppRequest struct {
 	Address string
 	Name    string
@@ -69,3 +76,115 @@ type NotifyAppRequest struct {
 	RootHash RootHash
 	Parcel   Parcel
 }
+
+// DownloadStatus - state of the data retrieval for single root hash
+type DownloadStatus string
+
+const (
+	// DownloadPending - root hash is received but retrieval didn't start yet
+	DownloadPending DownloadStatus = "pending"
+	// DownloadFetching - object headers and objects are being retrieved
+	DownloadFetching DownloadStatus = "fetching"
+	// DownloadVerifying - all data is retrieved and signature is being checked
+	DownloadVerifying DownloadStatus = "verifying"
+	// DownloadComplete - data is retrieved and signature is valid
+	DownloadComplete DownloadStatus = "complete"
+	// DownloadFailed - retrieval or signature verification failed
+	DownloadFailed DownloadStatus = "failed"
+)
+
+// Download model
+type Download struct {
+	RootHash  RootHash       `json:"rootHash"`
+	Status    DownloadStatus `json:"status"`
+	Size      uint64         `json:"size"`
+	Error     string         `json:"error"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// FeedState - whether node keeps retrieving new sequences of the feed
+type FeedState string
+
+const (
+	// FeedActive - new sequences are retrieved
+	FeedActive FeedState = "active"
+	// FeedOverQuota - latest sequence was refused for exceeding quota, previous sequence is kept
+	FeedOverQuota FeedState = "over-quota"
+	// FeedEvicted - latest sequence was refused for exceeding quota and stored data of the feed was removed
+	FeedEvicted FeedState = "evicted"
+	// FeedPaused - latest sequence was refused for exceeding quota and no new sequence is retrieved until resumed
+	FeedPaused FeedState = "paused"
+)
+
+// Feed model
+type Feed struct {
+	Publisher       string    `json:"publisher"`
+	State           FeedState `json:"state"`
+	Reason          string    `json:"reason,omitempty"`
+	RefusedRootHash *RootHash `json:"refusedRootHash,omitempty"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// FeedStatus - feed together with its latest stored sequence
+type FeedStatus struct {
+	Feed
+	Sequence uint64 `json:"sequence"`
+	Size     uint64 `json:"size"`
+}
+
+// Health - result of node checks, each check reports HealthOK or problem it found
+type Health struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+const (
+	// HealthOK - all checks passed
+	HealthOK = "ok"
+	// HealthFailing - at least one check failed
+	HealthFailing = "failing"
+)
+
+// TrackerStatus - health of tracker as seen by the node
+type TrackerStatus struct {
+	Address  string `json:"address"`
+	Priority int    `json:"priority"`
+	Healthy  bool   `json:"healthy"`
+	Failures int    `json:"failures"`
+	// CheckedAt - time of the last request or health check, zero if tracker wasn't contacted yet
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// QueueStatus - number of root hashes being retrieved and waiting for retrieval of their feed to finish
+type QueueStatus struct {
+	Running int `json:"running"`
+	Waiting int `json:"waiting"`
+}
+
+// NodeStatus - summary of node state
+type NodeStatus struct {
+	PubKey    string          `json:"pubKey"`
+	Transport string          `json:"transport"`
+	Address   string          `json:"address"`
+	Health    Health          `json:"health"`
+	Trackers  []TrackerStatus `json:"trackers"`
+	Feeds     []FeedStatus    `json:"feeds"`
+	Downloads []Download      `json:"downloads"`
+	Queue     QueueStatus     `json:"queue"`
+}
+
+// Subscription - publisher whose root hashes are accepted by the node
+type Subscription struct {
+	Publisher string `json:"publisher"`
+	// Configured - subscription comes from config file, so it can't be removed through the CLI
+	Configured bool      `json:"configured,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// BlockedPublisher - publisher whose root hashes are refused even if it's subscribed
+type BlockedPublisher struct {
+	Publisher string `json:"publisher"`
+	// Configured - publisher is blocked in config file, so it can't be unblocked through the CLI
+	Configured bool      `json:"configured,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
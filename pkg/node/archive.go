@@ -0,0 +1,267 @@
+package node
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/SkycoinProject/cxo-2/pkg/archive"
+	"github.com/SkycoinProject/cxo-2/pkg/errors"
+	"github.com/SkycoinProject/cxo-2/pkg/logging"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/parcel"
+	"github.com/SkycoinProject/cxo-2/pkg/signature"
+)
+
+// ExportedRootHash - find root hash of the feed that can be exported, latest one if sequence is not set.
+// Only data of the latest sequence is kept, so older root hashes are exported only if their data is still complete.
+func (s *Service) ExportedRootHash(publisher string, sequence *uint64) (model.RootHash, error) {
+	var rootHash model.RootHash
+	var err error
+	if sequence == nil {
+		rootHash, err = s.db.GetLatestRootHash(publisher)
+	} else {
+		rootHash, err = s.db.GetRootHash(fmt.Sprintf("%s_%v", publisher, *sequence))
+	}
+	if err != nil {
+		return model.RootHash{}, err
+	}
+
+	if err := s.checkDAG(rootHash.ObjectHeaderHash, make(map[string]struct{})); err != nil {
+		return model.RootHash{}, fmt.Errorf("data of root hash with key: %v is not complete: %v", rootHash.Key(), err)
+	}
+	return rootHash, nil
+}
+
+// ExportArchive - write root hash with all of its headers and objects to archive. Each object is written right
+// after the header referencing it.
+func (s *Service) ExportArchive(w io.Writer, rootHash model.RootHash) error {
+	writer := archive.NewWriter(w)
+	if err := writer.WriteRootHash(rootHash); err != nil {
+		return err
+	}
+	if err := s.exportHeader(writer, rootHash.ObjectHeaderHash, make(map[string]struct{})); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func (s *Service) exportHeader(writer *archive.Writer, hash string, exported map[string]struct{}) error {
+	if _, ok := exported[hash]; ok {
+		return nil
+	}
+	exported[hash] = struct{}{}
+
+	header, err := s.db.GetObjectHeader(hash)
+	if err != nil {
+		return fmt.Errorf("fetching object header with hash: %v failed due to error: %v", hash, err)
+	}
+	if err := writer.WriteObjectHeader(hash, header); err != nil {
+		return err
+	}
+
+	if len(header.ObjectHash) > 0 {
+		if _, ok := exported[header.ObjectHash]; !ok {
+			exported[header.ObjectHash] = struct{}{}
+			object, err := s.db.GetObject(header.ObjectHash)
+			if err != nil {
+				return fmt.Errorf("fetching object with hash: %v failed due to error: %v", header.ObjectHash, err)
+			}
+			if err := writer.WriteObject(header.ObjectHash, object); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, ref := range header.ExternalReferences {
+		if err := s.exportHeader(writer, ref, exported); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportArchive - load feed snapshot from archive into the store. Root hash has to pass the same validation as
+// root hash received from tracker, every header and object has to match its hash and signature has to be valid
+// for the complete data before root hash is stored. Feed is locked for the whole import, so that it doesn't
+// interleave with download of the same feed, and anything stored is rolled back if import fails.
+func (s *Service) ImportArchive(r io.Reader) (model.RootHash, error) {
+	reader := archive.NewReader(r)
+	entry, err := reader.Next()
+	if err != nil {
+		return model.RootHash{}, err
+	}
+	rootHash := entry.RootHash
+
+	unlock := s.feeds.lock(rootHash.Publisher)
+	defer unlock()
+
+	if _, err := s.db.GetRootHash(rootHash.Key()); err == nil {
+		s.rootHashLogger(rootHash).Info("Imported root hash already exists")
+		return rootHash, nil
+	}
+	// importing doesn't need subscription, since it's requested by node owner, but blocked publishers are refused
+	if err := s.checkPublisherNotBlocked(rootHash.Publisher); err != nil {
+		return rootHash, err
+	}
+	if err := s.validateRootHash(rootHash); err != nil {
+		return rootHash, err
+	}
+	if err := s.checkSignatureVersion(rootHash); err != nil {
+		return rootHash, err
+	}
+	// with root hash signature data can be refused before anything is stored
+	if rootHash.SignatureVersion == signature.RootHashVersion {
+		if err := signature.VerifyRootHash(rootHash); err != nil {
+			return rootHash, fmt.Errorf("root hash signature verification failed due to error: %v", err)
+		}
+	}
+	// owner resumes paused or evicted feed first, then the refused sequence is retrieved or imported again
+	if err := s.checkFeedRefusing(rootHash, model.FeedPaused, model.FeedEvicted); err != nil {
+		return rootHash, err
+	}
+
+	imported := &importedData{}
+	if err := s.importEntries(reader, rootHash, imported); err != nil {
+		s.rollbackImport(rootHash, imported)
+		return rootHash, err
+	}
+	// archive contains whole data of the sequence, anything missing means it's not complete
+	if err := s.checkSignature(rootHash); err != nil {
+		s.rollbackImport(rootHash, imported)
+		return rootHash, fmt.Errorf("imported data is not valid: %v", err)
+	}
+	if err := s.db.SaveRootHash(rootHash); err != nil {
+		s.rollbackImport(rootHash, imported)
+		return rootHash, fmt.Errorf("saving root hash with key: %v failed due to error: %v", rootHash.Key(), err)
+	}
+	s.metrics.gcReclaimedBytes.Add(float64(s.db.RemoveUnreferencedObjects(rootHash.Key(), true)))
+	if err := s.saveDownloadStatus(rootHash, model.DownloadComplete, nil); err != nil {
+		s.rootHashLogger(rootHash).WithError(err).Error("Saving download failed")
+	}
+	s.notifyRegisteredApps(rootHash)
+	return rootHash, nil
+}
+
+// importedData - changes made by import, so they can be reverted if it fails
+type importedData struct {
+	headers      []string
+	objects      []string
+	previousKeys map[string]string
+}
+
+// importEntries - store headers and objects of archive. Headers that are already stored are only moved to the
+// imported sequence and stored objects are kept, same as when sequence is downloaded. Root header, which is the
+// first one in archive, has to fit into quota before anything is stored.
+func (s *Service) importEntries(reader *archive.Reader, rootHash model.RootHash, imported *importedData) error {
+	// objects are saved with the header that references them, which always precedes them in archive
+	objectHeaders := make(map[string]string)
+	headers := make(map[string]model.ObjectHeader)
+	admitted := false
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch entry.Type {
+		case archive.ObjectHeaderEntry:
+			if err := parcel.CheckHash(entry.ObjectHeader, entry.Hash); err != nil {
+				return fmt.Errorf("archive contains invalid object header: %v", err)
+			}
+			if err := parcel.CheckHeaderSizes(entry.ObjectHeader); err != nil {
+				return fmt.Errorf("archive contains object header: %v with invalid size fields: %v", entry.Hash, err)
+			}
+			if !admitted {
+				if entry.Hash != rootHash.ObjectHeaderHash {
+					return fmt.Errorf("archive doesn't start with root object header: %v", rootHash.ObjectHeaderHash)
+				}
+				if err := s.admitRootHash(rootHash, parcel.TotalSize(entry.ObjectHeader)); err != nil {
+					return err
+				}
+				admitted = true
+			}
+			if err := s.importHeader(entry.Hash, rootHash, entry.ObjectHeader, imported); err != nil {
+				return err
+			}
+			if len(entry.ObjectHeader.ObjectHash) > 0 {
+				objectHeaders[entry.ObjectHeader.ObjectHash] = entry.Hash
+				headers[entry.Hash] = entry.ObjectHeader
+			}
+		case archive.ObjectEntry:
+			headerHash, ok := objectHeaders[entry.Hash]
+			if !ok {
+				return fmt.Errorf("archive contains object: %v that isn't referenced by preceding object header", entry.Hash)
+			}
+			if err := parcel.CheckHash(entry.Object, entry.Hash); err != nil {
+				return fmt.Errorf("archive contains invalid object: %v", err)
+			}
+			if err := parcel.CheckObjectSize(headers[headerHash], entry.Object); err != nil {
+				return fmt.Errorf("archive contains object: %v that doesn't match its header: %v", entry.Hash, err)
+			}
+			exists, err := s.db.HasObject(entry.Hash)
+			if err != nil {
+				return fmt.Errorf("checking object with hash: %v in db failed due to error: %v", entry.Hash, err)
+			}
+			if exists {
+				continue
+			}
+			if err := s.db.SaveObject(entry.Hash, headerHash, entry.Object); err != nil {
+				return fmt.Errorf("saving object with hash: %v failed due to error: %v", entry.Hash, err)
+			}
+			imported.objects = append(imported.objects, entry.Hash)
+		default:
+			return fmt.Errorf("archive contains more than one root hash")
+		}
+	}
+}
+
+func (s *Service) importHeader(hash string, rootHash model.RootHash, header model.ObjectHeader,
+	imported *importedData) error {
+	previousKey, err := s.db.GetObjectHeaderRootHashKey(hash)
+	if err == nil {
+		if previousKey == rootHash.Key() {
+			return nil
+		}
+		if err := s.db.UpdateObjectHeaderRootHashKey(hash, rootHash.Key()); err != nil {
+			return fmt.Errorf("updating object header with hash: %v failed due to error: %v", hash, err)
+		}
+		if imported.previousKeys == nil {
+			imported.previousKeys = make(map[string]string)
+		}
+		imported.previousKeys[hash] = previousKey
+		return nil
+	}
+	if err != errors.ErrCannotFindObjectHeader {
+		return fmt.Errorf("fetching object header with hash: %v from db failed due to error: %v", hash, err)
+	}
+
+	if err := s.db.SaveObjectHeader(hash, rootHash, header); err != nil {
+		return fmt.Errorf("saving object header with hash: %v failed due to error: %v", hash, err)
+	}
+	imported.headers = append(imported.headers, hash)
+	return nil
+}
+
+// rollbackImport - remove headers and objects stored by failed import and move headers it took over back to
+// sequences they belonged to
+func (s *Service) rollbackImport(rootHash model.RootHash, imported *importedData) {
+	logger := s.rootHashLogger(rootHash)
+	for _, hash := range imported.objects {
+		if err := s.db.RemoveObject(hash); err != nil {
+			logger.WithField(logging.HashField, hash).WithError(err).Error("Removing imported object failed")
+		}
+	}
+	for _, hash := range imported.headers {
+		if err := s.db.RemoveObjectHeader(hash); err != nil {
+			logger.WithField(logging.HashField, hash).WithError(err).Error("Removing imported object header failed")
+		}
+	}
+	for hash, key := range imported.previousKeys {
+		if err := s.db.UpdateObjectHeaderRootHashKey(hash, key); err != nil {
+			logger.WithField(logging.HashField, hash).WithError(err).Error("Restoring object header failed")
+		}
+	}
+}
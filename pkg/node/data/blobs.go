@@ -0,0 +1,111 @@
+package data
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// length of the object hash prefix used as subdirectory name, so that no directory holds too many files
+const blobPrefixLength = 2
+
+// blobDir - keeps object bytes as files named by object hash, outside of the bolt db.
+// Object with hash "ab12..." is stored in "<path>/ab/ab12...".
+type blobDir struct {
+	path   string
+	logger log.FieldLogger
+}
+
+func newBlobDir(path string, logger log.FieldLogger) (*blobDir, error) {
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to create objects directory: %v due to error: %v", path, err)
+	}
+	return &blobDir{path: path, logger: logger}, nil
+}
+
+func (b *blobDir) filePath(hash string) (string, error) {
+	// hash becomes part of the path so anything other than hex encoded hash is refused
+	if len(hash) <= blobPrefixLength {
+		return "", fmt.Errorf("invalid object hash: %q", hash)
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", fmt.Errorf("invalid object hash: %q", hash)
+	}
+	return filepath.Join(b.path, hash[:blobPrefixLength], hash), nil
+}
+
+// write - store object bytes atomically. Data is written and synced to temporary file which is then renamed,
+// so object file is either missing or complete even if node stops while writing.
+func (b *blobDir) write(hash string, data []byte) error {
+	path, err := b.filePath(hash)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, hash+".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	b.syncDir(dir)
+	return nil
+}
+
+func (b *blobDir) read(hash string) ([]byte, error) {
+	path, err := b.filePath(hash)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(path)
+}
+
+func (b *blobDir) remove(hash string) error {
+	path, err := b.filePath(hash)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// syncDir - persist directory entry of renamed file. Not every platform supports syncing directories,
+// so failure is only logged
+func (b *blobDir) syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		b.logger.WithField("directory", dir).WithError(err).Debug("opening directory for sync failed")
+		return
+	}
+	if err := d.Sync(); err != nil {
+		b.logger.WithField("directory", dir).WithError(err).Debug("syncing directory failed")
+	}
+	_ = d.Close()
+}
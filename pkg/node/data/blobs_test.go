@@ -0,0 +1,173 @@
+package data
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SkycoinProject/cxo-2/pkg/errors"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+)
+
+func newTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "cxo-data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestBlobDirFilePath(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+	blobs, err := newBlobDir(dir, newTestLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		expected string
+	}{
+		{name: "hash is sharded by prefix", hash: "ab12cd", expected: filepath.Join(dir, "ab", "ab12cd")},
+		{name: "upper case hex", hash: "AB12CD", expected: filepath.Join(dir, "AB", "AB12CD")},
+		{name: "empty hash", hash: ""},
+		{name: "hash not longer than prefix", hash: "ab"},
+		{name: "odd length", hash: "ab12c"},
+		{name: "non hex characters", hash: "zz12cd"},
+		{name: "path traversal", hash: "../../etc/passwd"},
+		{name: "path separator", hash: "ab/12cd"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path, err := blobs.filePath(tc.hash)
+			if tc.expected == "" {
+				if err == nil {
+					t.Fatalf("expected hash to be refused, got path: %v", path)
+				}
+				if err := blobs.write(tc.hash, []byte("data")); err == nil {
+					t.Fatal("expected writing object with invalid hash to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path != tc.expected {
+				t.Fatalf("expected path: %v, got: %v", tc.expected, path)
+			}
+		})
+	}
+}
+
+func TestBlobDir(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+	blobs, err := newBlobDir(dir, newTestLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const hash = "ab12cd"
+	for _, data := range [][]byte{[]byte("data"), []byte("overwritten data")} {
+		if err := blobs.write(hash, data); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := ioutil.ReadFile(filepath.Join(dir, "ab", hash))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(stored, data) {
+			t.Fatalf("expected stored data: %q, got: %q", data, stored)
+		}
+		read, err := blobs.read(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(read, data) {
+			t.Fatalf("expected read data: %q, got: %q", data, read)
+		}
+	}
+
+	// temporary files are renamed, so only the object file is left
+	files, err := ioutil.ReadDir(filepath.Join(dir, "ab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected only object file in objects directory, got %v files", len(files))
+	}
+
+	if err := blobs.remove(hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blobs.read(hash); !os.IsNotExist(err) {
+		t.Fatalf("expected removed object to be missing, got error: %v", err)
+	}
+	if err := blobs.remove(hash); err != nil {
+		t.Fatalf("expected removing missing object to succeed, got error: %v", err)
+	}
+}
+
+func TestGetObject(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+	dbPath := filepath.Join(dir, "cxo.db")
+	objectsPath := filepath.Join(dir, "objects")
+
+	// objects stored before objects directory was used
+	boltData, closeBolt, err := NewBoltData(dbPath, newTestLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := model.Object{Length: 6, Data: []byte("legacy")}
+	if err := boltData.SaveObject("aa01", "header", legacy); err != nil {
+		t.Fatal(err)
+	}
+	closeBolt()
+
+	fsData, closeFs, err := NewFilesystemData(dbPath, objectsPath, newTestLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFs()
+	stored := model.Object{Length: 6, Data: []byte("stored")}
+	missing := model.Object{Length: 7, Data: []byte("missing")}
+	empty := model.Object{}
+	for hash, object := range map[string]model.Object{"bb02": stored, "cc03": missing, "dd04": empty} {
+		if err := fsData.SaveObject(hash, "header", object); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(filepath.Join(objectsPath, "cc", "cc03")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		expected []byte
+		err      error
+	}{
+		{name: "object kept in database", hash: "aa01", expected: legacy.Data},
+		{name: "object kept in objects directory", hash: "bb02", expected: stored.Data},
+		{name: "object file missing", hash: "cc03", err: errors.ErrCannotFindObject},
+		// empty object is returned without reading its file
+		{name: "empty object", hash: "dd04"},
+		{name: "unknown object", hash: "ee05", err: errors.ErrCannotFindObject},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			object, err := fsData.GetObject(tc.hash)
+			if err != tc.err {
+				t.Fatalf("expected error: %v, got: %v", tc.err, err)
+			}
+			if !bytes.Equal(object.Data, tc.expected) {
+				t.Fatalf("expected data: %q, got: %q", tc.expected, object.Data)
+			}
+		})
+	}
+}
@@ -2,61 +2,109 @@ package data
 
 import (
 	"fmt"
+	"time"
 
 	storm "github.com/asdine/storm/v3"
+	bolt "go.etcd.io/bbolt"
 
 	log "github.com/sirupsen/logrus"
 )
 
-// DB global store variable
-var DB *storm.DB
+// how long to wait for the lock on db file, held by another running node
+const boltLockTimeout = 3 * time.Second
 
-const (
-	databaseName = "cxo-node.db"
-)
-
-// Init - initialization of bolt db
-func Init() func() {
-	var err error
-	DB, err = storm.Open(databaseName)
+// openBolt - open bolt db on the given path and make sure all buckets exist
+func openBolt(path string, logger log.FieldLogger) (*storm.DB, error) {
+	db, err := storm.Open(path, storm.BoltOptions(0600, &bolt.Options{Timeout: boltLockTimeout}))
 	if err != nil {
-		log.Fatal("Failed to connect to database", err)
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+
+	logger.Info("Database connected. Checking schema version...")
+	if err = migrate(db, path, logger); err != nil {
+		_ = db.Close()
+		return nil, err
 	}
 
-	log.Info("Database connected. Checking buckets...")
-	if err = initBuckets(); err != nil {
-		log.Fatal("buckets initialization failed due to error: ", err)
+	logger.Info("Checking buckets...")
+	if err = initBuckets(db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("buckets initialization failed due to error: %v", err)
 	}
 
+	return db, nil
+}
+
+func closeBolt(db *storm.DB, logger log.FieldLogger) func() {
 	return func() {
-		log.Info("Disconnecting database")
-		if err := DB.Close(); err != nil {
-			log.Fatal("closing db failed due to error: ", err)
+		logger.Info("Disconnecting database")
+		if err := db.Close(); err != nil {
+			logger.WithError(err).Error("closing db failed")
+			return
 		}
-		log.Debug("Database disconnected")
+		logger.Debug("Database disconnected")
 	}
 }
 
-func initBuckets() error {
-	err := DB.Init(&rootHashDAO{})
+func initBuckets(db *storm.DB) error {
+	err := db.Init(&rootHashDAO{})
 	if err != nil {
 		return fmt.Errorf("could not create root hash bucket: %v", err)
 	}
 
-	err = DB.Init(&objectHeaderDAO{})
+	err = db.Init(&objectHeaderDAO{})
 	if err != nil {
 		return fmt.Errorf("could not create object header bucket: %v", err)
 	}
 
-	err = DB.Init(&objectDAO{})
+	err = db.Init(&objectDAO{})
 	if err != nil {
 		return fmt.Errorf("could not create object bucket: %v", err)
 	}
 
-	err = DB.Init(&app{})
+	err = db.Init(&downloadDAO{})
+	if err != nil {
+		return fmt.Errorf("could not create download bucket: %v", err)
+	}
+
+	err = db.Init(&feedDAO{})
+	if err != nil {
+		return fmt.Errorf("could not create feed bucket: %v", err)
+	}
+
+	err = db.Init(&subscriptionDAO{})
+	if err != nil {
+		return fmt.Errorf("could not create subscription bucket: %v", err)
+	}
+
+	err = db.Init(&blockedPublisherDAO{})
+	if err != nil {
+		return fmt.Errorf("could not create blocked publisher bucket: %v", err)
+	}
+
+	err = db.Init(&app{})
 	if err != nil {
 		return fmt.Errorf("could not create app bucket: %v", err)
 	}
 
 	return nil
 }
+
+// bucketKeys - keys of all records stored in the given bucket, without loading the records
+func bucketKeys(db *storm.DB, bucketName string) ([]string, error) {
+	var keys []string
+	err := db.Bolt.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			// nil value marks nested bucket, used by storm for indexes and metadata
+			if v != nil {
+				keys = append(keys, string(k))
+			}
+			return nil
+		})
+	})
+	return keys, err
+}
@@ -6,6 +6,12 @@ import (
 	"github.com/SkycoinProject/cxo-2/pkg/model"
 )
 
+// buckets in which storm keeps DAOs, named by their type and keyed by ID
+const (
+	objectHeaderBucket = "objectHeaderDAO"
+	objectBucket       = "objectDAO"
+)
+
 type rootHashDAO struct {
 	ID       string
 	RootHash model.RootHash
@@ -24,6 +30,27 @@ type objectDAO struct {
 	Object           model.Object
 }
 
+type downloadDAO struct {
+	ID       string
+	Status   model.DownloadStatus `storm:"index"`
+	Download model.Download
+}
+
+type feedDAO struct {
+	ID   string
+	Feed model.Feed
+}
+
+type subscriptionDAO struct {
+	ID           string
+	Subscription model.Subscription
+}
+
+type blockedPublisherDAO struct {
+	ID               string
+	BlockedPublisher model.BlockedPublisher
+}
+
 type objectInfo struct {
 	ID   string
 	Path string `storm:"index"`
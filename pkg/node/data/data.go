@@ -1,15 +1,20 @@
 package data
 
 import (
+	"fmt"
+	"os"
 	"strings"
 	"time"
 
+	"github.com/SkycoinProject/cxo-2/pkg/config"
 	"github.com/SkycoinProject/cxo-2/pkg/errors"
+	"github.com/SkycoinProject/cxo-2/pkg/logging"
 
 	"github.com/SkycoinProject/cxo-2/pkg/model"
 	storm "github.com/asdine/storm/v3"
 	"github.com/asdine/storm/v3/q"
 	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
 )
 
 type Data interface {
@@ -18,22 +23,81 @@ type Data interface {
 	SaveObject(hash, objectHeaderHash string, object model.Object) error
 	UpdateObjectHeaderRootHashKey(hash string, rootHashKey string) error
 	GetRootHash(hash string) (model.RootHash, error)
+	GetLatestRootHash(publisher string) (model.RootHash, error)
 	GetObjectHeader(hash string) (model.ObjectHeader, error)
 	GetObject(hash string) (model.Object, error)
+	GetObjectHeaderRootHashKey(hash string) (string, error)
+	GetObjectHeaderHashOfObject(hash string) (string, error)
+	HasObject(hash string) (bool, error)
 	FindNewObjectHeaderHashes(rootHashKey string, timestamp time.Time) (map[string]struct{}, error)
-	RemoveUnreferencedObjects(rootHashKey string, isValidSignature bool)
+	RemoveUnreferencedObjects(rootHashKey string, isValidSignature bool) uint64
 	RegisterApp(address, name string) error
 	GetAllRegisteredApps() ([]string, error)
+	SaveDownload(download model.Download) error
+	GetDownload(rootHashKey string) (model.Download, error)
+	GetUnfinishedDownloads() ([]model.Download, error)
+	GetAllRootHashes() ([]model.RootHash, error)
+	GetAllObjectHeaderHashes() ([]string, error)
+	GetAllObjectHashes() ([]string, error)
+	RemoveObjectHeader(hash string) error
+	RemoveObject(hash string) error
+	RemoveFeedData(publisher string) (uint64, error)
+	SaveFeed(feed model.Feed) error
+	GetFeed(publisher string) (model.Feed, error)
+	GetAllFeeds() ([]model.Feed, error)
+	SaveSubscription(subscription model.Subscription) error
+	GetSubscription(publisher string) (model.Subscription, error)
+	GetAllSubscriptions() ([]model.Subscription, error)
+	RemoveSubscription(publisher string) error
+	SaveBlockedPublisher(blocked model.BlockedPublisher) error
+	GetBlockedPublisher(publisher string) (model.BlockedPublisher, error)
+	GetAllBlockedPublishers() ([]model.BlockedPublisher, error)
+	RemoveBlockedPublisher(publisher string) error
+	Ping() error
+	Size() (uint64, error)
 }
 
+// Open - create data store for the storage backend selected in configuration, which logs to the logger.
+// Returned function releases resources held by the store.
+func Open(cfg config.StorageConfig, logger log.FieldLogger) (Data, func(), error) {
+	switch cfg.Backend {
+	case config.MemoryStorage:
+		return NewMemoryData(logger), func() {}, nil
+	case config.FilesystemStorage:
+		return NewFilesystemData(cfg.DatabasePath, cfg.ObjectsPath, logger)
+	default:
+		return NewBoltData(cfg.DatabasePath, logger)
+	}
+}
+
+// store - bolt db backed data store
 type store struct {
 	db *storm.DB
+	// blobs keeps object bytes when set, otherwise they are stored in bolt db together with object metadata
+	blobs  *blobDir
+	logger log.FieldLogger
 }
 
-func DefaultData() Data {
-	return store{
-		db: DB,
+// NewBoltData - store all data in bolt db on the given path
+func NewBoltData(dbPath string, logger log.FieldLogger) (Data, func(), error) {
+	db, err := openBolt(dbPath, logger)
+	if err != nil {
+		return nil, nil, err
 	}
+	return store{db: db, logger: logger}, closeBolt(db, logger), nil
+}
+
+// NewFilesystemData - store object bytes as files in objects directory and everything else in bolt db on the given path
+func NewFilesystemData(dbPath, objectsPath string, logger log.FieldLogger) (Data, func(), error) {
+	blobs, err := newBlobDir(objectsPath, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := openBolt(dbPath, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	return store{db: db, blobs: blobs, logger: logger}, closeBolt(db, logger), nil
 }
 
 func (s store) SaveRootHash(rootHash model.RootHash) error {
@@ -53,6 +117,12 @@ func (s store) SaveObjectHeader(hash string, rootHash model.RootHash, objectHead
 }
 
 func (s store) SaveObject(hash, objectHeaderHash string, object model.Object) error {
+	if s.blobs != nil {
+		if err := s.blobs.write(hash, object.Data); err != nil {
+			return fmt.Errorf("writing object with hash: %v to objects directory failed due to error: %v", hash, err)
+		}
+		object.Data = nil
+	}
 	return s.db.Save(&objectDAO{
 		ID:               hash,
 		ObjectHeaderHash: objectHeaderHash,
@@ -71,7 +141,7 @@ func (s store) GetRootHash(key string) (model.RootHash, error) {
 		if dbError == storm.ErrNotFound {
 			err = errors.ErrCannotFindRootHash
 		} else {
-			log.Errorf("could not retrieve root hash with key: %v due to error: %v", key, err)
+			s.logger.WithField("key", key).WithError(dbError).Error("could not retrieve root hash")
 			err = dbError
 		}
 	}
@@ -79,6 +149,30 @@ func (s store) GetRootHash(key string) (model.RootHash, error) {
 	return rootHashDAO.RootHash, err
 }
 
+// GetLatestRootHash returns stored root hash with the highest sequence for the given publisher
+func (s store) GetLatestRootHash(publisher string) (model.RootHash, error) {
+	var rootHashDAOs []rootHashDAO
+	if err := s.db.Prefix("ID", publisher+"_", &rootHashDAOs); err != nil {
+		if err == storm.ErrNotFound {
+			return model.RootHash{}, errors.ErrCannotFindRootHash
+		}
+		s.logger.WithField(logging.PublisherField, publisher).WithError(err).Error("could not retrieve root hashes")
+		return model.RootHash{}, err
+	}
+
+	if len(rootHashDAOs) == 0 {
+		return model.RootHash{}, errors.ErrCannotFindRootHash
+	}
+
+	latest := rootHashDAOs[0].RootHash
+	for _, dao := range rootHashDAOs[1:] {
+		if dao.RootHash.Sequence > latest.Sequence {
+			latest = dao.RootHash
+		}
+	}
+	return latest, nil
+}
+
 func (s store) GetObjectHeader(hash string) (model.ObjectHeader, error) {
 	objectHeaderDAO := objectHeaderDAO{}
 	var err error
@@ -86,7 +180,7 @@ func (s store) GetObjectHeader(hash string) (model.ObjectHeader, error) {
 		if dbError == storm.ErrNotFound {
 			err = errors.ErrCannotFindObjectHeader
 		} else {
-			log.Errorf("could not retrieve object header with hash: %v due to error: %v", hash, err)
+			s.logger.WithField(logging.HashField, hash).WithError(dbError).Error("could not retrieve object header")
 			err = dbError
 		}
 	}
@@ -101,12 +195,62 @@ func (s store) GetObject(hash string) (model.Object, error) {
 		if dbError == storm.ErrNotFound {
 			err = errors.ErrCannotFindObject
 		} else {
-			log.Errorf("could not retrieve object with hash: %v due to error: %v", hash, err)
+			s.logger.WithField(logging.HashField, hash).WithError(dbError).Error("could not retrieve object")
 			err = dbError
 		}
+		return objectDAO.Object, err
 	}
 
-	return objectDAO.Object, err
+	// objects stored before objects directory was used keep their bytes in bolt db until migrated
+	if s.blobs != nil && len(objectDAO.Object.Data) == 0 && objectDAO.Object.Length > 0 {
+		data, blobErr := s.blobs.read(hash)
+		if blobErr != nil {
+			if os.IsNotExist(blobErr) {
+				return model.Object{}, errors.ErrCannotFindObject
+			}
+			s.logger.WithField(logging.HashField, hash).WithError(blobErr).Error("could not read object")
+			return model.Object{}, blobErr
+		}
+		objectDAO.Object.Data = data
+	}
+
+	return objectDAO.Object, nil
+}
+
+// GetObjectHeaderRootHashKey returns key of the root hash object header was last retrieved for
+func (s store) GetObjectHeaderRootHashKey(hash string) (string, error) {
+	objectHeaderDAO := objectHeaderDAO{}
+	if err := s.db.One("ID", hash, &objectHeaderDAO); err != nil {
+		if err == storm.ErrNotFound {
+			return "", errors.ErrCannotFindObjectHeader
+		}
+		s.logger.WithField(logging.HashField, hash).WithError(err).Error("could not retrieve object header")
+		return "", err
+	}
+	return objectHeaderDAO.RootHashKey, nil
+}
+
+// GetObjectHeaderHashOfObject returns hash of the object header object was retrieved for, without loading its data
+func (s store) GetObjectHeaderHashOfObject(hash string) (string, error) {
+	objectDAO := objectDAO{}
+	if err := s.db.One("ID", hash, &objectDAO); err != nil {
+		if err == storm.ErrNotFound {
+			return "", errors.ErrCannotFindObject
+		}
+		s.logger.WithField(logging.HashField, hash).WithError(err).Error("could not retrieve object")
+		return "", err
+	}
+	return objectDAO.ObjectHeaderHash, nil
+}
+
+// HasObject checks if object is stored without loading its data
+func (s store) HasObject(hash string) (bool, error) {
+	exists, err := s.db.KeyExists(objectBucket, hash)
+	if err != nil && err != storm.ErrNotFound {
+		s.logger.WithField(logging.HashField, hash).WithError(err).Error("could not check object")
+		return false, err
+	}
+	return exists, nil
 }
 
 func (s store) FindNewObjectHeaderHashes(rootHashKey string, timestamp time.Time) (map[string]struct{}, error) {
@@ -115,7 +259,8 @@ func (s store) FindNewObjectHeaderHashes(rootHashKey string, timestamp time.Time
 		if err == storm.ErrNotFound {
 			return make(map[string]struct{}, 0), nil
 		}
-		log.Errorf("could not retrieve object headers with root hash key: %v and timestamp %v due to error: %v", rootHashKey, timestamp, err)
+		s.logger.WithFields(log.Fields{"key": rootHashKey, "timestamp": timestamp}).WithError(err).
+			Error("could not retrieve object headers")
 		return make(map[string]struct{}, 0), err
 	}
 
@@ -127,16 +272,17 @@ func (s store) FindNewObjectHeaderHashes(rootHashKey string, timestamp time.Time
 	return headerHashes, nil
 }
 
-// Remove headers and object infos and return slice of paths for dirs and files that should be removed
-func (s store) RemoveUnreferencedObjects(latestRootHashKey string, isValidSignature bool) {
+// Remove headers and object infos of the publisher that are not on the latest sequence and return number of
+// reclaimed object bytes
+func (s store) RemoveUnreferencedObjects(latestRootHashKey string, isValidSignature bool) uint64 {
 	var objectHeaderDAOs []objectHeaderDAO
 	pubKey := strings.Split(latestRootHashKey, "_")[0]
 
 	//selecting all headers for specific pub key
 	if err := s.db.Prefix("RootHashKey", pubKey, &objectHeaderDAOs); err != nil {
 		if err != storm.ErrNotFound {
-			log.Errorf("could not retrieve unreferenced object headers due to error: %v", err)
-			return
+			s.logger.WithField(logging.PublisherField, pubKey).WithError(err).Error("could not retrieve unreferenced object headers")
+			return 0
 		}
 	}
 
@@ -153,44 +299,62 @@ func (s store) RemoveUnreferencedObjects(latestRootHashKey string, isValidSignat
 		unreferencedObjectHeaderDAOs = objectHeaderDAOs
 	}
 
-	for _, headerDAO := range unreferencedObjectHeaderDAOs {
+	reclaimed := s.removeHeaders(unreferencedObjectHeaderDAOs)
+
+	if !isValidSignature {
+		rootHashDAO := rootHashDAO{}
+		if err := s.db.One("ID", latestRootHashKey, &rootHashDAO); err != nil {
+			if err != storm.ErrNotFound {
+				s.logger.WithField("key", latestRootHashKey).WithError(err).Error("could not retrieve root hash")
+			}
+		} else {
+			_ = s.db.DeleteStruct(&rootHashDAO)
+		}
+	}
+	return reclaimed
+}
+
+// removeHeaders - remove object headers together with their objects, failures are only logged. Number of bytes of
+// removed objects is returned.
+func (s store) removeHeaders(headerDAOs []objectHeaderDAO) uint64 {
+	var removed uint64
+	for _, headerDAO := range headerDAOs {
 		objectHeader := headerDAO.ObjectHeader
 		if len(objectHeader.ObjectHash) > 0 {
 			var objectDAO objectDAO
 			if err := s.db.One("ID", objectHeader.ObjectHash, &objectDAO); err != nil {
 				if err != storm.ErrNotFound {
-					log.Errorf("Fetching object with hash: %v failed with error: %v", objectHeader.ObjectHash, err)
+					s.logger.WithField(logging.HashField, objectHeader.ObjectHash).WithError(err).Error("Fetching object failed")
 				}
 			} else {
 				if err := s.db.DeleteStruct(&objectDAO); err != nil {
-					log.Errorf("Deleting object with hash: %v failed with error: %v", objectDAO.ID, err)
+					s.logger.WithField(logging.HashField, objectDAO.ID).WithError(err).Error("Deleting object failed")
+				} else {
+					removed += objectDAO.Object.Length
+					if s.blobs != nil {
+						if err := s.blobs.remove(objectDAO.ID); err != nil {
+							s.logger.WithField(logging.HashField, objectDAO.ID).WithError(err).Error("Deleting object file failed")
+						}
+					}
 				}
 			}
 		}
 		if err := s.db.DeleteStruct(&headerDAO); err != nil {
-			log.Errorf("Deleting object header with hash: %v failed with error: %v", headerDAO.ID, err)
-		}
-	}
-
-	if !isValidSignature {
-		rootHashDAO := rootHashDAO{}
-		if err := s.db.One("ID", latestRootHashKey, &rootHashDAO); err != nil {
-			log.Errorf("could not retrieve root hash with key: %v due to error: %v", latestRootHashKey, err)
-		} else {
-			_ = s.db.DeleteStruct(&rootHashDAO)
+			s.logger.WithField(logging.HashField, headerDAO.ID).WithError(err).Error("Deleting object header failed")
 		}
 	}
+	return removed
 }
 
 func (s store) RegisterApp(address, name string) error {
 	var existingApp app
 	if err := s.db.One("Address", address, &existingApp); err != nil {
 		if err != storm.ErrNotFound {
-			log.Errorf("fetching app with address: %v failed due to error: %v", address, err)
+			s.logger.WithField("address", address).WithError(err).Error("fetching app failed")
 			return err
 		}
 	} else {
-		log.Infof("app with address: %v already registered...", address)
+		s.logger.WithField("address", address).Info("app is already registered")
 		return nil
 	}
 	return s.db.Save(&app{
@@ -204,7 +368,7 @@ func (s store) GetAllRegisteredApps() ([]string, error) {
 	var addresses []string
 	var err error
 	if err = s.db.All(&apps); err != nil {
-		log.Error("could not retrieve registered apps due to error: ", err)
+		s.logger.WithError(err).Error("could not retrieve registered apps")
 		return addresses, err
 	}
 
@@ -214,3 +378,247 @@ func (s store) GetAllRegisteredApps() ([]string, error) {
 
 	return addresses, nil
 }
+
+func (s store) SaveDownload(download model.Download) error {
+	return s.db.Save(&downloadDAO{
+		ID:       download.RootHash.Key(),
+		Status:   download.Status,
+		Download: download,
+	})
+}
+
+func (s store) GetDownload(rootHashKey string) (model.Download, error) {
+	downloadDAO := downloadDAO{}
+	var err error
+	if dbError := s.db.One("ID", rootHashKey, &downloadDAO); dbError != nil {
+		if dbError == storm.ErrNotFound {
+			err = errors.ErrCannotFindDownload
+		} else {
+			s.logger.WithField("key", rootHashKey).WithError(dbError).Error("could not retrieve download")
+			err = dbError
+		}
+	}
+
+	return downloadDAO.Download, err
+}
+
+// GetUnfinishedDownloads returns downloads that were interrupted before reaching complete or failed state
+func (s store) GetUnfinishedDownloads() ([]model.Download, error) {
+	var downloadDAOs []downloadDAO
+	unfinished := []model.DownloadStatus{model.DownloadPending, model.DownloadFetching, model.DownloadVerifying}
+	if err := s.db.Select(q.In("Status", unfinished)).Find(&downloadDAOs); err != nil {
+		if err == storm.ErrNotFound {
+			return []model.Download{}, nil
+		}
+		s.logger.WithError(err).Error("could not retrieve unfinished downloads")
+		return []model.Download{}, err
+	}
+
+	downloads := make([]model.Download, 0, len(downloadDAOs))
+	for _, dao := range downloadDAOs {
+		downloads = append(downloads, dao.Download)
+	}
+
+	return downloads, nil
+}
+
+func (s store) GetAllRootHashes() ([]model.RootHash, error) {
+	var rootHashDAOs []rootHashDAO
+	if err := s.db.All(&rootHashDAOs); err != nil {
+		s.logger.WithError(err).Error("could not retrieve root hashes")
+		return []model.RootHash{}, err
+	}
+
+	rootHashes := make([]model.RootHash, 0, len(rootHashDAOs))
+	for _, dao := range rootHashDAOs {
+		rootHashes = append(rootHashes, dao.RootHash)
+	}
+	return rootHashes, nil
+}
+
+// GetAllObjectHeaderHashes returns hashes of all stored object headers without loading them
+func (s store) GetAllObjectHeaderHashes() ([]string, error) {
+	return bucketKeys(s.db, objectHeaderBucket)
+}
+
+// GetAllObjectHashes returns hashes of all stored objects without loading them
+func (s store) GetAllObjectHashes() ([]string, error) {
+	return bucketKeys(s.db, objectBucket)
+}
+
+func (s store) RemoveObjectHeader(hash string) error {
+	var objectHeaderDAO objectHeaderDAO
+	if err := s.db.One("ID", hash, &objectHeaderDAO); err != nil {
+		if err == storm.ErrNotFound {
+			return errors.ErrCannotFindObjectHeader
+		}
+		return err
+	}
+	return s.db.DeleteStruct(&objectHeaderDAO)
+}
+
+func (s store) RemoveObject(hash string) error {
+	var objectDAO objectDAO
+	if err := s.db.One("ID", hash, &objectDAO); err != nil {
+		if err == storm.ErrNotFound {
+			return errors.ErrCannotFindObject
+		}
+		return err
+	}
+	if err := s.db.DeleteStruct(&objectDAO); err != nil {
+		return err
+	}
+	if s.blobs != nil {
+		return s.blobs.remove(hash)
+	}
+	return nil
+}
+
+// RemoveFeedData removes all object headers and objects of the publisher and returns number of reclaimed object
+// bytes. Root hashes are kept, so that sequences older than the removed one are still refused.
+func (s store) RemoveFeedData(publisher string) (uint64, error) {
+	var objectHeaderDAOs []objectHeaderDAO
+	if err := s.db.Prefix("RootHashKey", publisher+"_", &objectHeaderDAOs); err != nil {
+		if err == storm.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return s.removeHeaders(objectHeaderDAOs), nil
+}
+
+func (s store) SaveFeed(feed model.Feed) error {
+	return s.db.Save(&feedDAO{
+		ID:   feed.Publisher,
+		Feed: feed,
+	})
+}
+
+func (s store) GetFeed(publisher string) (model.Feed, error) {
+	feedDAO := feedDAO{}
+	if err := s.db.One("ID", publisher, &feedDAO); err != nil {
+		if err == storm.ErrNotFound {
+			return model.Feed{}, errors.ErrCannotFindFeed
+		}
+		s.logger.WithField(logging.PublisherField, publisher).WithError(err).Error("could not retrieve feed")
+		return model.Feed{}, err
+	}
+	return feedDAO.Feed, nil
+}
+
+func (s store) GetAllFeeds() ([]model.Feed, error) {
+	var feedDAOs []feedDAO
+	if err := s.db.All(&feedDAOs); err != nil {
+		s.logger.WithError(err).Error("could not retrieve feeds")
+		return []model.Feed{}, err
+	}
+
+	feeds := make([]model.Feed, 0, len(feedDAOs))
+	for _, dao := range feedDAOs {
+		feeds = append(feeds, dao.Feed)
+	}
+	return feeds, nil
+}
+
+func (s store) SaveSubscription(subscription model.Subscription) error {
+	return s.db.Save(&subscriptionDAO{
+		ID:           subscription.Publisher,
+		Subscription: subscription,
+	})
+}
+
+func (s store) GetSubscription(publisher string) (model.Subscription, error) {
+	subscriptionDAO := subscriptionDAO{}
+	if err := s.db.One("ID", publisher, &subscriptionDAO); err != nil {
+		if err == storm.ErrNotFound {
+			return model.Subscription{}, errors.ErrCannotFindSubscription
+		}
+		s.logger.WithField(logging.PublisherField, publisher).WithError(err).Error("could not retrieve subscription")
+		return model.Subscription{}, err
+	}
+	return subscriptionDAO.Subscription, nil
+}
+
+func (s store) GetAllSubscriptions() ([]model.Subscription, error) {
+	var subscriptionDAOs []subscriptionDAO
+	if err := s.db.All(&subscriptionDAOs); err != nil {
+		s.logger.WithError(err).Error("could not retrieve subscriptions")
+		return []model.Subscription{}, err
+	}
+
+	subscriptions := make([]model.Subscription, 0, len(subscriptionDAOs))
+	for _, dao := range subscriptionDAOs {
+		subscriptions = append(subscriptions, dao.Subscription)
+	}
+	return subscriptions, nil
+}
+
+func (s store) RemoveSubscription(publisher string) error {
+	if err := s.db.DeleteStruct(&subscriptionDAO{ID: publisher}); err != nil {
+		if err == storm.ErrNotFound {
+			return errors.ErrCannotFindSubscription
+		}
+		return err
+	}
+	return nil
+}
+
+func (s store) SaveBlockedPublisher(blocked model.BlockedPublisher) error {
+	return s.db.Save(&blockedPublisherDAO{
+		ID:               blocked.Publisher,
+		BlockedPublisher: blocked,
+	})
+}
+
+func (s store) GetBlockedPublisher(publisher string) (model.BlockedPublisher, error) {
+	blockedDAO := blockedPublisherDAO{}
+	if err := s.db.One("ID", publisher, &blockedDAO); err != nil {
+		if err == storm.ErrNotFound {
+			return model.BlockedPublisher{}, errors.ErrCannotFindBlocked
+		}
+		s.logger.WithField(logging.PublisherField, publisher).WithError(err).Error("could not retrieve blocked publisher")
+		return model.BlockedPublisher{}, err
+	}
+	return blockedDAO.BlockedPublisher, nil
+}
+
+func (s store) GetAllBlockedPublishers() ([]model.BlockedPublisher, error) {
+	var blockedDAOs []blockedPublisherDAO
+	if err := s.db.All(&blockedDAOs); err != nil {
+		s.logger.WithError(err).Error("could not retrieve blocked publishers")
+		return []model.BlockedPublisher{}, err
+	}
+
+	blocked := make([]model.BlockedPublisher, 0, len(blockedDAOs))
+	for _, dao := range blockedDAOs {
+		blocked = append(blocked, dao.BlockedPublisher)
+	}
+	return blocked, nil
+}
+
+func (s store) RemoveBlockedPublisher(publisher string) error {
+	if err := s.db.DeleteStruct(&blockedPublisherDAO{ID: publisher}); err != nil {
+		if err == storm.ErrNotFound {
+			return errors.ErrCannotFindBlocked
+		}
+		return err
+	}
+	return nil
+}
+
+// Ping - check that bolt db is open and can be read
+func (s store) Ping() error {
+	return s.db.Bolt.View(func(tx *bolt.Tx) error {
+		return nil
+	})
+}
+
+// Size - size of bolt db file, objects kept in objects directory are not included
+func (s store) Size() (uint64, error) {
+	var size int64
+	err := s.db.Bolt.View(func(tx *bolt.Tx) error {
+		size = tx.Size()
+		return nil
+	})
+	return uint64(size), err
+}
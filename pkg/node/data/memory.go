@@ -0,0 +1,442 @@
+package data
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/errors"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// memoryStore - data store kept only in memory, used for tests and local development
+type memoryStore struct {
+	mux        sync.RWMutex
+	rootHashes map[string]model.RootHash
+	headers    map[string]objectHeaderDAO
+	objects    map[string]objectDAO
+	downloads  map[string]model.Download
+	feeds      map[string]model.Feed
+	subscribed map[string]model.Subscription
+	blocked    map[string]model.BlockedPublisher
+	apps       []app
+	logger     log.FieldLogger
+}
+
+// NewMemoryData - create data store that is lost once node stops
+func NewMemoryData(logger log.FieldLogger) Data {
+	return &memoryStore{
+		logger:     logger,
+		rootHashes: make(map[string]model.RootHash),
+		headers:    make(map[string]objectHeaderDAO),
+		objects:    make(map[string]objectDAO),
+		downloads:  make(map[string]model.Download),
+		feeds:      make(map[string]model.Feed),
+		subscribed: make(map[string]model.Subscription),
+		blocked:    make(map[string]model.BlockedPublisher),
+	}
+}
+
+func (m *memoryStore) SaveRootHash(rootHash model.RootHash) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.rootHashes[rootHash.Key()] = rootHash
+	return nil
+}
+
+func (m *memoryStore) SaveObjectHeader(hash string, rootHash model.RootHash, objectHeader model.ObjectHeader) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.headers[hash] = objectHeaderDAO{
+		ID:           hash,
+		RootHashKey:  rootHash.Key(),
+		Timestamp:    rootHash.Timestamp,
+		ObjectHeader: objectHeader,
+	}
+	return nil
+}
+
+func (m *memoryStore) SaveObject(hash, objectHeaderHash string, object model.Object) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.objects[hash] = objectDAO{
+		ID:               hash,
+		ObjectHeaderHash: objectHeaderHash,
+		Object:           object,
+	}
+	return nil
+}
+
+func (m *memoryStore) UpdateObjectHeaderRootHashKey(hash string, rootHashKey string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	header, ok := m.headers[hash]
+	if !ok {
+		return errors.ErrCannotFindObjectHeader
+	}
+	header.RootHashKey = rootHashKey
+	m.headers[hash] = header
+	return nil
+}
+
+func (m *memoryStore) GetRootHash(key string) (model.RootHash, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	rootHash, ok := m.rootHashes[key]
+	if !ok {
+		return model.RootHash{}, errors.ErrCannotFindRootHash
+	}
+	return rootHash, nil
+}
+
+func (m *memoryStore) GetLatestRootHash(publisher string) (model.RootHash, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	var latest *model.RootHash
+	for _, rootHash := range m.rootHashes {
+		if rootHash.Publisher != publisher {
+			continue
+		}
+		if latest == nil || rootHash.Sequence > latest.Sequence {
+			r := rootHash
+			latest = &r
+		}
+	}
+	if latest == nil {
+		return model.RootHash{}, errors.ErrCannotFindRootHash
+	}
+	return *latest, nil
+}
+
+func (m *memoryStore) GetObjectHeader(hash string) (model.ObjectHeader, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	header, ok := m.headers[hash]
+	if !ok {
+		return model.ObjectHeader{}, errors.ErrCannotFindObjectHeader
+	}
+	return header.ObjectHeader, nil
+}
+
+func (m *memoryStore) GetObject(hash string) (model.Object, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	object, ok := m.objects[hash]
+	if !ok {
+		return model.Object{}, errors.ErrCannotFindObject
+	}
+	return object.Object, nil
+}
+
+func (m *memoryStore) GetObjectHeaderRootHashKey(hash string) (string, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	header, ok := m.headers[hash]
+	if !ok {
+		return "", errors.ErrCannotFindObjectHeader
+	}
+	return header.RootHashKey, nil
+}
+
+func (m *memoryStore) GetObjectHeaderHashOfObject(hash string) (string, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	object, ok := m.objects[hash]
+	if !ok {
+		return "", errors.ErrCannotFindObject
+	}
+	return object.ObjectHeaderHash, nil
+}
+
+func (m *memoryStore) HasObject(hash string) (bool, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	_, ok := m.objects[hash]
+	return ok, nil
+}
+
+func (m *memoryStore) FindNewObjectHeaderHashes(rootHashKey string, timestamp time.Time) (map[string]struct{}, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	headerHashes := make(map[string]struct{})
+	for hash, header := range m.headers {
+		if header.RootHashKey == rootHashKey && header.Timestamp.Equal(timestamp) {
+			headerHashes[hash] = struct{}{}
+		}
+	}
+	return headerHashes, nil
+}
+
+func (m *memoryStore) RemoveUnreferencedObjects(latestRootHashKey string, isValidSignature bool) uint64 {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	pubKey := strings.Split(latestRootHashKey, "_")[0]
+
+	var reclaimed uint64
+	for hash, header := range m.headers {
+		if !strings.HasPrefix(header.RootHashKey, pubKey) {
+			continue
+		}
+		// if signature is valid only objects that are not on latest sequence are removed
+		if isValidSignature && header.RootHashKey == latestRootHashKey {
+			continue
+		}
+		reclaimed += m.removeHeader(hash, header.ObjectHeader)
+	}
+
+	if !isValidSignature {
+		delete(m.rootHashes, latestRootHashKey)
+	}
+	return reclaimed
+}
+
+// removeHeader - remove object header together with its object and return number of removed object bytes
+func (m *memoryStore) removeHeader(hash string, header model.ObjectHeader) uint64 {
+	var removed uint64
+	if len(header.ObjectHash) > 0 {
+		if object, ok := m.objects[header.ObjectHash]; ok {
+			removed = object.Object.Length
+			delete(m.objects, header.ObjectHash)
+		}
+	}
+	delete(m.headers, hash)
+	return removed
+}
+
+func (m *memoryStore) RegisterApp(address, name string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	for _, existingApp := range m.apps {
+		if existingApp.Address == address {
+			m.logger.WithField("address", address).Info("app is already registered")
+			return nil
+		}
+	}
+	m.apps = append(m.apps, app{
+		Pk:      len(m.apps) + 1,
+		Address: address,
+		Name:    name,
+	})
+	return nil
+}
+
+func (m *memoryStore) GetAllRegisteredApps() ([]string, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	var addresses []string
+	for _, app := range m.apps {
+		addresses = append(addresses, app.Address)
+	}
+	return addresses, nil
+}
+
+func (m *memoryStore) SaveDownload(download model.Download) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.downloads[download.RootHash.Key()] = download
+	return nil
+}
+
+func (m *memoryStore) GetDownload(rootHashKey string) (model.Download, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	download, ok := m.downloads[rootHashKey]
+	if !ok {
+		return model.Download{}, errors.ErrCannotFindDownload
+	}
+	return download, nil
+}
+
+func (m *memoryStore) GetUnfinishedDownloads() ([]model.Download, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	downloads := []model.Download{}
+	for _, download := range m.downloads {
+		switch download.Status {
+		case model.DownloadPending, model.DownloadFetching, model.DownloadVerifying:
+			downloads = append(downloads, download)
+		}
+	}
+	return downloads, nil
+}
+
+func (m *memoryStore) GetAllRootHashes() ([]model.RootHash, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	rootHashes := make([]model.RootHash, 0, len(m.rootHashes))
+	for _, rootHash := range m.rootHashes {
+		rootHashes = append(rootHashes, rootHash)
+	}
+	return rootHashes, nil
+}
+
+func (m *memoryStore) GetAllObjectHeaderHashes() ([]string, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	hashes := make([]string, 0, len(m.headers))
+	for hash := range m.headers {
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+func (m *memoryStore) GetAllObjectHashes() ([]string, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	hashes := make([]string, 0, len(m.objects))
+	for hash := range m.objects {
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+func (m *memoryStore) RemoveObjectHeader(hash string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if _, ok := m.headers[hash]; !ok {
+		return errors.ErrCannotFindObjectHeader
+	}
+	delete(m.headers, hash)
+	return nil
+}
+
+func (m *memoryStore) RemoveObject(hash string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if _, ok := m.objects[hash]; !ok {
+		return errors.ErrCannotFindObject
+	}
+	delete(m.objects, hash)
+	return nil
+}
+
+func (m *memoryStore) RemoveFeedData(publisher string) (uint64, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	var reclaimed uint64
+	for hash, header := range m.headers {
+		if !strings.HasPrefix(header.RootHashKey, publisher+"_") {
+			continue
+		}
+		reclaimed += m.removeHeader(hash, header.ObjectHeader)
+	}
+	return reclaimed, nil
+}
+
+func (m *memoryStore) SaveFeed(feed model.Feed) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.feeds[feed.Publisher] = feed
+	return nil
+}
+
+func (m *memoryStore) GetFeed(publisher string) (model.Feed, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	feed, ok := m.feeds[publisher]
+	if !ok {
+		return model.Feed{}, errors.ErrCannotFindFeed
+	}
+	return feed, nil
+}
+
+func (m *memoryStore) GetAllFeeds() ([]model.Feed, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	feeds := make([]model.Feed, 0, len(m.feeds))
+	for _, feed := range m.feeds {
+		feeds = append(feeds, feed)
+	}
+	return feeds, nil
+}
+
+func (m *memoryStore) SaveSubscription(subscription model.Subscription) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.subscribed[subscription.Publisher] = subscription
+	return nil
+}
+
+func (m *memoryStore) GetSubscription(publisher string) (model.Subscription, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	subscription, ok := m.subscribed[publisher]
+	if !ok {
+		return model.Subscription{}, errors.ErrCannotFindSubscription
+	}
+	return subscription, nil
+}
+
+func (m *memoryStore) GetAllSubscriptions() ([]model.Subscription, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	subscriptions := make([]model.Subscription, 0, len(m.subscribed))
+	for _, subscription := range m.subscribed {
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, nil
+}
+
+func (m *memoryStore) RemoveSubscription(publisher string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if _, ok := m.subscribed[publisher]; !ok {
+		return errors.ErrCannotFindSubscription
+	}
+	delete(m.subscribed, publisher)
+	return nil
+}
+
+func (m *memoryStore) SaveBlockedPublisher(blocked model.BlockedPublisher) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.blocked[blocked.Publisher] = blocked
+	return nil
+}
+
+func (m *memoryStore) GetBlockedPublisher(publisher string) (model.BlockedPublisher, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	blocked, ok := m.blocked[publisher]
+	if !ok {
+		return model.BlockedPublisher{}, errors.ErrCannotFindBlocked
+	}
+	return blocked, nil
+}
+
+func (m *memoryStore) GetAllBlockedPublishers() ([]model.BlockedPublisher, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	blocked := make([]model.BlockedPublisher, 0, len(m.blocked))
+	for _, publisher := range m.blocked {
+		blocked = append(blocked, publisher)
+	}
+	return blocked, nil
+}
+
+func (m *memoryStore) RemoveBlockedPublisher(publisher string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if _, ok := m.blocked[publisher]; !ok {
+		return errors.ErrCannotFindBlocked
+	}
+	delete(m.blocked, publisher)
+	return nil
+}
+
+// Ping - memory store is always available
+func (m *memoryStore) Ping() error {
+	return nil
+}
+
+// Size - number of stored object bytes
+func (m *memoryStore) Size() (uint64, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	var size uint64
+	for _, object := range m.objects {
+		size += object.Object.Length
+	}
+	return size, nil
+}
@@ -0,0 +1,198 @@
+package data
+
+import (
+	"fmt"
+	"os"
+
+	storm "github.com/asdine/storm/v3"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/SkycoinProject/cxo-2/pkg/logging"
+	log "github.com/sirupsen/logrus"
+)
+
+// how many bytes of compacted db are written in single transaction
+const compactTxMaxSize = 64 * 1024 * 1024
+
+// MigrateObjects - move object bytes kept inside bolt db on dbPath to objects directory and compact the db file,
+// since bolt never gives freed pages back to the file system. Returns number of moved objects.
+// Node using the db must be stopped while migrating.
+func MigrateObjects(dbPath, objectsPath string, logger log.FieldLogger) (int, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return 0, fmt.Errorf("unable to find database: %v", err)
+	}
+
+	blobs, err := newBlobDir(objectsPath, logger)
+	if err != nil {
+		return 0, err
+	}
+
+	db, err := openBolt(dbPath, logger)
+	if err != nil {
+		return 0, err
+	}
+
+	moved, err := moveObjectsToBlobs(db, blobs)
+	if err != nil {
+		_ = db.Close()
+		return moved, err
+	}
+
+	compactPath := dbPath + ".compact"
+	// left over by migration that was interrupted while compacting
+	if err := os.Remove(compactPath); err != nil && !os.IsNotExist(err) {
+		_ = db.Close()
+		return moved, fmt.Errorf("removing unfinished compacted database failed due to error: %v", err)
+	}
+	err = compactBolt(db.Bolt, compactPath, compactTxMaxSize)
+	if closeErr := db.Close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(compactPath)
+		return moved, fmt.Errorf("compacting database failed due to error: %v", err)
+	}
+
+	if err := os.Rename(compactPath, dbPath); err != nil {
+		return moved, fmt.Errorf("replacing database with compacted one failed due to error: %v", err)
+	}
+	return moved, nil
+}
+
+func moveObjectsToBlobs(db *storm.DB, blobs *blobDir) (int, error) {
+	// only hashes are collected up front so objects are loaded one at a time
+	hashes, err := bucketKeys(db, objectBucket)
+	if err != nil {
+		return 0, fmt.Errorf("listing stored objects failed due to error: %v", err)
+	}
+
+	moved := 0
+	for _, hash := range hashes {
+		var dao objectDAO
+		if err := db.One("ID", hash, &dao); err != nil {
+			return moved, fmt.Errorf("fetching object with hash: %v failed due to error: %v", hash, err)
+		}
+		if len(dao.Object.Data) == 0 {
+			continue
+		}
+
+		if err := blobs.write(hash, dao.Object.Data); err != nil {
+			return moved, fmt.Errorf("writing object with hash: %v failed due to error: %v", hash, err)
+		}
+		dao.Object.Data = nil
+		if err := db.Save(&dao); err != nil {
+			return moved, fmt.Errorf("updating object with hash: %v failed due to error: %v", hash, err)
+		}
+		moved++
+		blobs.logger.WithField(logging.HashField, hash).Debug("object moved to objects directory")
+	}
+	return moved, nil
+}
+
+// compactBolt - copy every bucket of the src db to new db on dstPath, leaving out free pages. Copied data is committed
+// in transactions of at most maxTxSize bytes, so compacting large db doesn't keep all of it in memory at once.
+func compactBolt(src *bolt.DB, dstPath string, maxTxSize int) error {
+	dst, err := bolt.Open(dstPath, 0600, nil)
+	if err != nil {
+		return err
+	}
+
+	w := &batchWriter{db: dst, maxSize: maxTxSize}
+	err = src.View(func(srcTx *bolt.Tx) error {
+		if err := w.begin(); err != nil {
+			return err
+		}
+		err := srcTx.ForEach(func(name []byte, srcBucket *bolt.Bucket) error {
+			return copyBucket(w, srcBucket, [][]byte{name})
+		})
+		if err != nil {
+			_ = w.tx.Rollback()
+			return err
+		}
+		return w.tx.Commit()
+	})
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// copyBucket - copy src bucket to the bucket on the given path of dst db, including nested buckets
+func copyBucket(w *batchWriter, src *bolt.Bucket, path [][]byte) error {
+	// sequence is used by storm for auto incremented ids
+	if err := w.createBucket(path, src.Sequence()); err != nil {
+		return err
+	}
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return w.put(path, k, v)
+		}
+		childPath := append(append([][]byte{}, path...), k)
+		return copyBucket(w, src.Bucket(k), childPath)
+	})
+}
+
+// batchWriter - writes to db in transactions, committing current one and beginning the next one once it holds
+// maxSize bytes. Buckets are addressed by path from the root, since bucket handles are valid only within their transaction.
+type batchWriter struct {
+	db      *bolt.DB
+	tx      *bolt.Tx
+	size    int
+	maxSize int
+}
+
+func (w *batchWriter) begin() error {
+	tx, err := w.db.Begin(true)
+	if err != nil {
+		return err
+	}
+	w.tx = tx
+	w.size = 0
+	return nil
+}
+
+// reserve - make room for size bytes in current transaction, committing it if it's full
+func (w *batchWriter) reserve(size int) error {
+	if w.size > 0 && w.size+size > w.maxSize {
+		if err := w.tx.Commit(); err != nil {
+			return err
+		}
+		if err := w.begin(); err != nil {
+			return err
+		}
+	}
+	w.size += size
+	return nil
+}
+
+func (w *batchWriter) bucket(path [][]byte) *bolt.Bucket {
+	bucket := w.tx.Bucket(path[0])
+	for _, name := range path[1:] {
+		bucket = bucket.Bucket(name)
+	}
+	return bucket
+}
+
+func (w *batchWriter) createBucket(path [][]byte, sequence uint64) error {
+	if err := w.reserve(len(path[len(path)-1])); err != nil {
+		return err
+	}
+	var bucket *bolt.Bucket
+	var err error
+	if len(path) == 1 {
+		bucket, err = w.tx.CreateBucket(path[0])
+	} else {
+		bucket, err = w.bucket(path[:len(path)-1]).CreateBucket(path[len(path)-1])
+	}
+	if err != nil {
+		return err
+	}
+	return bucket.SetSequence(sequence)
+}
+
+func (w *batchWriter) put(path [][]byte, k, v []byte) error {
+	if err := w.reserve(len(k) + len(v)); err != nil {
+		return err
+	}
+	return w.bucket(path).Put(k, v)
+}
@@ -0,0 +1,205 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	storm "github.com/asdine/storm/v3"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestMigrateObjects(t *testing.T) {
+	objects := map[string]model.Object{
+		"aa01": {Length: 5, Data: []byte("first")},
+		"bb02": {Length: 6, Data: []byte("second")},
+		"cc03": {Length: 5, Data: []byte("third")},
+	}
+
+	tests := []struct {
+		name string
+		// interrupt - simulate migration interrupted before it finished
+		interrupt func(t *testing.T, db *storm.DB, blobs *blobDir, dbPath string)
+		moved     int
+	}{
+		{name: "objects kept in database", moved: 3},
+		{name: "interrupted after moving objects", moved: 1,
+			interrupt: func(t *testing.T, db *storm.DB, blobs *blobDir, dbPath string) {
+				moved, err := moveObjectsToBlobs(db, blobs)
+				if err != nil || moved != 3 {
+					t.Fatalf("expected 3 moved objects, got: %v, error: %v", moved, err)
+				}
+				// object written to objects directory, but still kept in database
+				var dao objectDAO
+				if err := db.One("ID", "bb02", &dao); err != nil {
+					t.Fatal(err)
+				}
+				dao.Object = objects["bb02"]
+				if err := db.Save(&dao); err != nil {
+					t.Fatal(err)
+				}
+			}},
+		{name: "interrupted while compacting", moved: 3,
+			interrupt: func(t *testing.T, db *storm.DB, blobs *blobDir, dbPath string) {
+				if err := ioutil.WriteFile(dbPath+".compact", []byte("unfinished"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := newTestDir(t)
+			defer os.RemoveAll(dir)
+			dbPath := filepath.Join(dir, "cxo.db")
+			objectsPath := filepath.Join(dir, "objects")
+
+			db, err := openBolt(dbPath, newTestLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for hash, object := range objects {
+				if err := db.Save(&objectDAO{ID: hash, ObjectHeaderHash: "header", Object: object}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tc.interrupt != nil {
+				blobs, err := newBlobDir(objectsPath, newTestLogger())
+				if err != nil {
+					t.Fatal(err)
+				}
+				tc.interrupt(t, db, blobs, dbPath)
+			}
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			moved, err := MigrateObjects(dbPath, objectsPath, newTestLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if moved != tc.moved {
+				t.Fatalf("expected %v moved objects, got: %v", tc.moved, moved)
+			}
+			if _, err := os.Stat(dbPath + ".compact"); !os.IsNotExist(err) {
+				t.Fatalf("expected compacted database to replace the original one, got error: %v", err)
+			}
+			// everything is already moved
+			if moved, err := MigrateObjects(dbPath, objectsPath, newTestLogger()); err != nil || moved != 0 {
+				t.Fatalf("expected no moved objects when migrating again, got: %v, error: %v", moved, err)
+			}
+
+			data, closeData, err := NewFilesystemData(dbPath, objectsPath, newTestLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closeData()
+			for hash, expected := range objects {
+				object, err := data.GetObject(hash)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(object.Data, expected.Data) {
+					t.Fatalf("expected data of object: %v to be: %q, got: %q", hash, expected.Data, object.Data)
+				}
+				var dao objectDAO
+				if err := data.(store).db.One("ID", hash, &dao); err != nil {
+					t.Fatal(err)
+				}
+				if len(dao.Object.Data) != 0 {
+					t.Fatalf("expected data of object: %v to be removed from database", hash)
+				}
+			}
+		})
+	}
+}
+
+func TestCompactBolt(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	src, err := bolt.Open(filepath.Join(dir, "src.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	err = src.Update(func(tx *bolt.Tx) error {
+		for i := 0; i < 3; i++ {
+			bucket, err := tx.CreateBucket([]byte(fmt.Sprintf("bucket%v", i)))
+			if err != nil {
+				return err
+			}
+			if err := bucket.SetSequence(uint64(i + 10)); err != nil {
+				return err
+			}
+			nested, err := bucket.CreateBucket([]byte("nested"))
+			if err != nil {
+				return err
+			}
+			for j := 0; j < 100; j++ {
+				if err := bucket.Put([]byte(fmt.Sprintf("key%v", j)), bytes.Repeat([]byte{byte(j)}, j)); err != nil {
+					return err
+				}
+				if err := nested.Put([]byte(fmt.Sprintf("nested%v", j)), []byte("value")); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, maxTxSize := range []int{1, 256, compactTxMaxSize} {
+		t.Run(fmt.Sprintf("transactions of %v bytes", maxTxSize), func(t *testing.T) {
+			dstPath := filepath.Join(dir, fmt.Sprintf("dst%v.db", maxTxSize))
+			if err := compactBolt(src, dstPath, maxTxSize); err != nil {
+				t.Fatal(err)
+			}
+			dst, err := bolt.Open(dstPath, 0600, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer dst.Close()
+
+			srcDump, dstDump := dumpBolt(t, src), dumpBolt(t, dst)
+			if len(srcDump) != len(dstDump) {
+				t.Fatalf("expected %v copied entries, got: %v", len(srcDump), len(dstDump))
+			}
+			for key, value := range srcDump {
+				if dstDump[key] != value {
+					t.Fatalf("expected %q to be copied as: %q, got: %q", key, value, dstDump[key])
+				}
+			}
+		})
+	}
+}
+
+// dumpBolt - values and sequences of buckets in db, keyed by their path
+func dumpBolt(t *testing.T, db *bolt.DB) map[string]string {
+	dump := make(map[string]string)
+	var walk func(bucket *bolt.Bucket, path string) error
+	walk = func(bucket *bolt.Bucket, path string) error {
+		dump[path+"#sequence"] = fmt.Sprint(bucket.Sequence())
+		return bucket.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return walk(bucket.Bucket(k), path+"/"+string(k))
+			}
+			dump[path+"/"+string(k)] = string(v)
+			return nil
+		})
+	}
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			return walk(bucket, string(name))
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dump
+}
@@ -0,0 +1,210 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	storm "github.com/asdine/storm/v3"
+	bolt "go.etcd.io/bbolt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	metaBucket       = "meta"
+	schemaVersionKey = "schemaVersion"
+	// bucket created by storm itself when opening db
+	stormInfoBucket = "__storm_db"
+)
+
+// migration - single step of upgrading stored data to the layout expected by current DAOs
+type migration struct {
+	version     int
+	description string
+	apply       func(db *storm.DB) error
+}
+
+// migrations are applied in order to every db with lower schema version. Each change of DAOs that breaks
+// existing data needs a new migration with the next version, already released migrations must never change.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "start tracking schema version of databases created before versioning was introduced",
+		apply:       func(db *storm.DB) error { return nil },
+	},
+	{
+		version:     2,
+		description: "subscribe to publishers of stored root hashes, since root hashes of other publishers are refused",
+		apply:       seedSubscriptions,
+	},
+}
+
+// seedSubscriptions - node used to accept root hashes of any publisher, so every publisher it has data of was
+// subscribed to through the tracker
+func seedSubscriptions(db *storm.DB) error {
+	var rootHashDAOs []rootHashDAO
+	if err := db.All(&rootHashDAOs); err != nil && err != storm.ErrNotFound {
+		return fmt.Errorf("fetching root hashes failed due to error: %v", err)
+	}
+
+	now := time.Now()
+	for _, dao := range rootHashDAOs {
+		publisher := dao.RootHash.Publisher
+		subscription := subscriptionDAO{
+			ID:           publisher,
+			Subscription: model.Subscription{Publisher: publisher, CreatedAt: now},
+		}
+		if err := db.Save(&subscription); err != nil {
+			return fmt.Errorf("saving subscription of publisher: %v failed due to error: %v", publisher, err)
+		}
+	}
+	return nil
+}
+
+func latestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+func schemaVersion(db *storm.DB) (int, error) {
+	var version int
+	if err := db.Get(metaBucket, schemaVersionKey, &version); err != nil {
+		if err == storm.ErrNotFound {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("reading schema version failed due to error: %v", err)
+	}
+	return version, nil
+}
+
+func setSchemaVersion(db *storm.DB, version int) error {
+	return db.Set(metaBucket, schemaVersionKey, version)
+}
+
+func pendingMigrations(db *storm.DB) ([]migration, error) {
+	isEmpty, err := isEmptyBolt(db)
+	if err != nil {
+		return nil, err
+	}
+	if isEmpty {
+		// new db is created with the latest layout
+		return nil, nil
+	}
+
+	version, err := schemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if version > latestSchemaVersion() {
+		return nil, fmt.Errorf("database schema version %v is newer than %v supported by this node", version, latestSchemaVersion())
+	}
+
+	var pending []migration
+	for _, m := range migrations {
+		if m.version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// migrate - back up db on dbPath and apply pending migrations in order, or just set the latest version for new db
+func migrate(db *storm.DB, dbPath string, logger log.FieldLogger) error {
+	pending, err := pendingMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	if len(pending) == 0 {
+		version, err := schemaVersion(db)
+		if err != nil {
+			return err
+		}
+		if version == 0 {
+			return setSchemaVersion(db, latestSchemaVersion())
+		}
+		return nil
+	}
+
+	backupPath, err := backupBolt(db, dbPath, pending[0].version-1)
+	if err != nil {
+		return fmt.Errorf("backing up database before migration failed due to error: %v", err)
+	}
+	logger.Infof("Database backed up to: %v", backupPath)
+
+	for _, m := range pending {
+		logger.Infof("Applying database migration %v: %v", m.version, m.description)
+		if err := m.apply(db); err != nil {
+			return fmt.Errorf("database migration %v failed due to error: %v, backup is available at: %v", m.version, err, backupPath)
+		}
+		if err := setSchemaVersion(db, m.version); err != nil {
+			return fmt.Errorf("storing schema version %v failed due to error: %v", m.version, err)
+		}
+	}
+	return nil
+}
+
+func isEmptyBolt(db *storm.DB) (bool, error) {
+	isEmpty := true
+	err := db.Bolt.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if string(name) != stormInfoBucket {
+				isEmpty = false
+			}
+			return nil
+		})
+	})
+	return isEmpty, err
+}
+
+func backupBolt(db *storm.DB, dbPath string, version int) (string, error) {
+	backupPath := fmt.Sprintf("%s.v%v-%s.bak", dbPath, version, time.Now().Format("20060102150405"))
+	err := db.Bolt.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(backupPath, 0600)
+	})
+	return backupPath, err
+}
+
+// PendingMigrations - describe migrations that would be applied to db on the given path, without applying them
+func PendingMigrations(dbPath string, logger log.FieldLogger) ([]string, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("unable to find database: %v", err)
+	}
+
+	db, err := storm.Open(dbPath, storm.BoltOptions(0600, &bolt.Options{Timeout: boltLockTimeout, ReadOnly: true}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.WithError(err).Error("closing db failed")
+		}
+	}()
+
+	pending, err := pendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptions := make([]string, 0, len(pending))
+	for _, m := range pending {
+		descriptions = append(descriptions, fmt.Sprintf("%v: %v", m.version, m.description))
+	}
+	return descriptions, nil
+}
+
+// Migrate - apply pending migrations to db on the given path. Same migrations are applied on every node startup,
+// so this is needed only to upgrade db without starting the node
+func Migrate(dbPath string, logger log.FieldLogger) error {
+	if _, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("unable to find database: %v", err)
+	}
+
+	db, err := openBolt(dbPath, logger)
+	if err != nil {
+		return err
+	}
+	closeBolt(db, logger)()
+	return nil
+}
@@ -0,0 +1,211 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	storm "github.com/asdine/storm/v3"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func newTestLogger() log.FieldLogger {
+	logger := log.New()
+	logger.SetLevel(log.WarnLevel)
+	return logger
+}
+
+// createTestBolt - bolt db in temp directory with root hashes of given publishers, stored with the given schema
+// version, or without any version as by nodes released before versioning was introduced if it's 0
+func createTestBolt(t *testing.T, dir string, version int, publishers ...string) string {
+	path := filepath.Join(dir, "cxo.db")
+	db, err := storm.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, publisher := range publishers {
+		dao := rootHashDAO{ID: publisher, RootHash: model.RootHash{Publisher: publisher, Sequence: 1}}
+		if err := db.Save(&dao); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if version > 0 {
+		if err := setSchemaVersion(db, version); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// readTestBolt - schema version and subscribed publishers of db on the given path
+func readTestBolt(t *testing.T, path string) (int, []string) {
+	db, err := storm.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	version, err := schemaVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var subscriptions []subscriptionDAO
+	if err := db.All(&subscriptions); err != nil && err != storm.ErrNotFound {
+		t.Fatal(err)
+	}
+	publishers := make([]string, 0, len(subscriptions))
+	for _, s := range subscriptions {
+		publishers = append(publishers, s.Subscription.Publisher)
+	}
+	return version, publishers
+}
+
+func backups(t *testing.T, path string) []string {
+	matches, err := filepath.Glob(path + ".v*.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    int
+		publishers []string
+		// pending - versions of migrations expected to be applied
+		pending []int
+		// subscribed - publishers expected to be subscribed after migration
+		subscribed []string
+		newer      bool
+	}{
+		{name: "database without schema version", publishers: []string{"publisher1", "publisher2"},
+			pending: []int{1, 2}, subscribed: []string{"publisher1", "publisher2"}},
+		{name: "database of previous version", version: 1, publishers: []string{"publisher"},
+			pending: []int{2}, subscribed: []string{"publisher"}},
+		// subscriptions of up to date database are left as they are
+		{name: "database of latest version", version: latestSchemaVersion(), publishers: []string{"publisher"},
+			subscribed: []string{}},
+		// new database is created with the latest layout, so it only gets the version
+		{name: "empty database", subscribed: []string{}},
+		{name: "database newer than node", version: latestSchemaVersion() + 1, publishers: []string{"publisher"},
+			newer: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "cxo-migrations")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			path := createTestBolt(t, dir, tc.version, tc.publishers...)
+			original, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// dry run
+			pending, err := PendingMigrations(path, newTestLogger())
+			if tc.newer {
+				if err == nil {
+					t.Fatal("expected newer database to be refused")
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if len(pending) != len(tc.pending) {
+				t.Fatalf("expected %v pending migrations, got: %v", len(tc.pending), pending)
+			}
+			for i, version := range tc.pending {
+				if pending[i] != migrationDescription(version) {
+					t.Fatalf("expected pending migration: %q, got: %q", migrationDescription(version), pending[i])
+				}
+			}
+			current, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(original, current) {
+				t.Fatal("listing pending migrations changed the database")
+			}
+
+			err = Migrate(path, newTestLogger())
+			if tc.newer {
+				if err == nil {
+					t.Fatal("expected newer database to be refused")
+				}
+				if version, _ := readTestBolt(t, path); version != tc.version {
+					t.Fatalf("expected schema version of refused database to stay %v, got: %v", tc.version, version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			version, subscribed := readTestBolt(t, path)
+			if version != latestSchemaVersion() {
+				t.Fatalf("expected schema version %v, got: %v", latestSchemaVersion(), version)
+			}
+			if !equalStrings(subscribed, tc.subscribed) {
+				t.Fatalf("expected subscriptions of: %v, got: %v", tc.subscribed, subscribed)
+			}
+
+			backupPaths := backups(t, path)
+			if len(tc.pending) == 0 {
+				if len(backupPaths) != 0 {
+					t.Fatalf("expected no backup without pending migrations, got: %v", backupPaths)
+				}
+				return
+			}
+			if len(backupPaths) != 1 {
+				t.Fatalf("expected single backup, got: %v", backupPaths)
+			}
+			// backup holds database as it was before migration
+			if version, subscribed := readTestBolt(t, backupPaths[0]); version != tc.version || len(subscribed) != 0 {
+				t.Fatalf("expected backup of version %v without subscriptions, got version %v, subscriptions of: %v",
+					tc.version, version, subscribed)
+			}
+
+			// applied migrations aren't applied again
+			if pending, err := PendingMigrations(path, newTestLogger()); err != nil || len(pending) != 0 {
+				t.Fatalf("expected no pending migrations after migrating, got: %v, error: %v", pending, err)
+			}
+		})
+	}
+}
+
+func migrationDescription(version int) string {
+	for _, m := range migrations {
+		if m.version == version {
+			return fmt.Sprintf("%v: %v", m.version, m.description)
+		}
+	}
+	return ""
+}
+
+// equalStrings - whether a and b hold the same strings, regardless of order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int)
+	for _, s := range a {
+		counts[s]++
+	}
+	for _, s := range b {
+		if counts[s] == 0 {
+			return false
+		}
+		counts[s]--
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/logging"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/tracker"
+	"github.com/SkycoinProject/cxo-2/pkg/transport"
+	log "github.com/sirupsen/logrus"
+)
+
+// routes of the dmsg server shared with trackers, so nodes can fetch data from each other the same way as from tracker
+const (
+	objectHeaderRoute = "/data/object/header"
+	objectRoute       = "/data/object"
+	peersRoute        = "/peers?pubKey="
+)
+
+// peersTTL - how long peers of the feed found through trackers are used before asking trackers again
+const peersTTL = 5 * time.Minute
+
+// peerSet - nodes that can be asked for data of each feed
+type peerSet struct {
+	mux   sync.Mutex
+	feeds map[string]*feedPeers
+}
+
+type feedPeers struct {
+	addresses []string
+	foundAt   time.Time
+}
+
+func newPeerSet() *peerSet {
+	return &peerSet{feeds: make(map[string]*feedPeers)}
+}
+
+// fetch - send request for data of the feed to its peers first and to trackers only if none of the peers
+// succeeds. Peers are not trusted any more than trackers, request has to check received data against its hash.
+func (s *Service) fetch(client *http.Client, feed string, request func(address string) error) error {
+	if s.config.P2P.Enabled {
+		for _, peer := range s.feedPeers(client, feed) {
+			err := request(peer)
+			if err == nil {
+				return nil
+			}
+			s.logger.WithFields(log.Fields{logging.PublisherField: feed, "peer": peer}).WithError(err).
+				Debug("Fetching data from peer failed")
+			s.peers.remove(feed, peer)
+		}
+	}
+	return s.trackers.Do(feed, request)
+}
+
+// feedPeers - addresses of configured peers and of nodes subscribed to the feed, found through trackers
+func (s *Service) feedPeers(client *http.Client, feed string) []string {
+	s.peers.mux.Lock()
+	found, ok := s.peers.feeds[feed]
+	s.peers.mux.Unlock()
+
+	if !ok || time.Since(found.foundAt) > peersTTL {
+		addresses, err := s.discoverPeers(client, feed)
+		if err != nil {
+			s.logger.WithField(logging.PublisherField, feed).WithError(err).Debug("Finding peers failed")
+		}
+		found = &feedPeers{addresses: addresses, foundAt: time.Now()}
+		s.peers.mux.Lock()
+		s.peers.feeds[feed] = found
+		s.peers.mux.Unlock()
+	}
+
+	s.peers.mux.Lock()
+	defer s.peers.mux.Unlock()
+	peers := make([]string, 0, len(s.config.P2P.Peers)+len(found.addresses))
+	for _, peer := range s.config.P2P.Peers {
+		peers = append(peers, peerURL(peer))
+	}
+	return append(peers, found.addresses...)
+}
+
+func (s *Service) discoverPeers(client *http.Client, feed string) ([]string, error) {
+	var resp model.GetPeersResponse
+	// knowing peers doesn't mean tracker has data of the feed, so it doesn't become the feed's tracker
+	err := s.trackers.Query(feed, func(address string) error {
+		httpResp, err := client.Get(fmt.Sprint(address, peersRoute, feed))
+		if err != nil {
+			return fmt.Errorf("request for peers failed due to error: %w", err)
+		}
+		defer httpResp.Body.Close()
+		// tracker without peer discovery is still healthy, it just doesn't know any peers
+		if httpResp.StatusCode == http.StatusNotFound {
+			resp.Peers = nil
+			return nil
+		}
+		if httpResp.StatusCode != http.StatusOK {
+			return tracker.NewStatusError("request for peers", httpResp)
+		}
+		body, err := ioutil.ReadAll(httpResp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading data: %w", err)
+		}
+		return json.Unmarshal(body, &resp)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	self := normalizedPeerURL(transport.Address(s.config))
+	addresses := make([]string, 0, len(resp.Peers))
+	for _, peer := range resp.Peers {
+		if normalizedPeerURL(peer) == self {
+			continue
+		}
+		addresses = append(addresses, peerURL(peer))
+	}
+	return addresses, nil
+}
+
+// remove - stop asking peer for data of the feed until peers are found again
+func (p *peerSet) remove(feed, address string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	found, ok := p.feeds[feed]
+	if !ok {
+		return
+	}
+	for i, peer := range found.addresses {
+		if peer == address {
+			found.addresses = append(found.addresses[:i:i], found.addresses[i+1:]...)
+			return
+		}
+	}
+}
+
+// peerURL - URL of peer given with scheme of its transport, or dmsg URL of peer given as <public key>:<port>
+func peerURL(address string) string {
+	if strings.Contains(address, "://") {
+		return address
+	}
+	return config.DMSGTransport + "://" + address
+}
+
+// normalizedPeerURL - scheme and host of peer's URL in lower case with explicit port, so that the same peer
+// given in different forms can be compared
+func normalizedPeerURL(address string) string {
+	u, err := url.Parse(peerURL(address))
+	if err != nil {
+		return address
+	}
+	scheme := strings.ToLower(u.Scheme)
+	port := u.Port()
+	if port == "" {
+		switch scheme {
+		case config.HTTPTransport:
+			port = "80"
+		case config.HTTPSTransport:
+			port = "443"
+		}
+	}
+	return scheme + "://" + net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
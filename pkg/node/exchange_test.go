@@ -0,0 +1,131 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// newPeersTracker - tracker that knows given peers of every feed, or doesn't support peer discovery if they're nil
+func newPeersTracker(t *testing.T, peers []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/peers" || peers == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(model.GetPeersResponse{Peers: peers}); err != nil {
+			t.Error(err)
+		}
+	}))
+}
+
+// newPeersService - node serving over HTTP on given address, with P2P exchange enabled
+func newPeersService(t *testing.T, address string, tracker *httptest.Server) *Service {
+	return newTestService(t, config.Config{
+		Trackers: []config.TrackerConfig{{Address: tracker.URL}},
+		Server:   config.ServerConfig{Transport: config.HTTPTransport, Address: address},
+		P2P:      config.P2PConfig{Enabled: true},
+	})
+}
+
+func TestDiscoverPeers(t *testing.T) {
+	peer, _ := cipher.GenerateKeyPair()
+
+	tests := []struct {
+		name     string
+		self     string
+		peers    []string
+		expected []string
+	}{
+		{name: "tracker without peer discovery", self: "127.0.0.1:8083", expected: []string{}},
+		{name: "node itself is excluded", self: "127.0.0.1:8083",
+			peers:    []string{"http://127.0.0.1:8083", "http://127.0.0.1:8084", peer.Hex() + ":8083"},
+			expected: []string{"http://127.0.0.1:8084", "dmsg://" + peer.Hex() + ":8083"}},
+		{name: "node itself given in other form is excluded", self: "localhost:80",
+			peers:    []string{"HTTP://LOCALHOST:80/", "http://localhost", "http://localhost:8080"},
+			expected: []string{"http://localhost:8080"}},
+		// address of node is prefix of the peer's one, but it's different peer
+		{name: "peer with longer port is kept", self: "127.0.0.1:80",
+			peers:    []string{"http://127.0.0.1:8080", "http://127.0.0.1:80"},
+			expected: []string{"http://127.0.0.1:8080"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := newPeersTracker(t, tc.peers)
+			defer tracker.Close()
+			s := newPeersService(t, tc.self, tracker)
+			addresses, err := s.discoverPeers(s.client, "publisher")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalStrings(addresses, tc.expected) {
+				t.Fatalf("expected peers: %v, got: %v", tc.expected, addresses)
+			}
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	const peer = "http://127.0.0.1:1"
+
+	tests := []struct {
+		name string
+		// failing - addresses whose requests fail
+		failing []string
+		// expected - addresses asked by the first and the second fetch
+		expected [][]string
+	}{
+		{name: "data fetched from peer", expected: [][]string{{peer}, {peer}}},
+		// peer that fails isn't asked again until peers are found again
+		{name: "failing peer falls back to tracker", failing: []string{peer},
+			expected: [][]string{{peer, "tracker"}, {"tracker"}}},
+		{name: "every source fails", failing: []string{peer, "tracker"},
+			expected: [][]string{{peer, "tracker"}, {"tracker"}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := newPeersTracker(t, []string{peer})
+			defer tracker.Close()
+			s := newPeersService(t, "127.0.0.1:8083", tracker)
+			for i, expected := range tc.expected {
+				var asked []string
+				err := s.fetch(s.client, "publisher", func(address string) error {
+					if address == tracker.URL {
+						address = "tracker"
+					}
+					asked = append(asked, address)
+					for _, failing := range tc.failing {
+						if failing == address {
+							return fmt.Errorf("request to: %v failed", address)
+						}
+					}
+					return nil
+				})
+				if (err != nil) != (len(tc.failing) == 2) {
+					t.Fatalf("unexpected error of fetch: %v", err)
+				}
+				if !equalSequence(asked, expected) {
+					t.Fatalf("expected fetch %v to ask: %v, got: %v", i+1, expected, asked)
+				}
+			}
+		})
+	}
+}
+
+func equalSequence(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,251 @@
+package node
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/SkycoinProject/cxo-2/pkg/errors"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/parcel"
+)
+
+// FsckReport - problems found in stored data by Fsck
+type FsckReport struct {
+	Feeds                int
+	ObjectHeaders        int
+	Objects              int
+	DanglingReferences   []string
+	CorruptedHeaders     []string
+	CorruptedObjects     []string
+	OrphanedHeaders      []string
+	OrphanedObjects      []string
+	IncompleteRootHashes []string
+	RepairedRootHashes   []string
+	RemovedHeaders       []string
+	RemovedObjects       []string
+}
+
+// HasProblems - whether any inconsistency was found in stored data
+func (r FsckReport) HasProblems() bool {
+	return len(r.DanglingReferences) > 0 || len(r.CorruptedHeaders) > 0 || len(r.CorruptedObjects) > 0 ||
+		len(r.OrphanedHeaders) > 0 || len(r.OrphanedObjects) > 0 || len(r.IncompleteRootHashes) > 0
+}
+
+// fsckWalk - state of walking stored DAGs, shared by all feeds so that common headers are checked only once
+type fsckWalk struct {
+	report *FsckReport
+	// reachableHeaders and referencedObjects - whether already checked header or object is complete, so data
+	// shared by several feeds is checked only once but reported incomplete for each of them
+	reachableHeaders  map[string]bool
+	referencedObjects map[string]bool
+	// tolerateMissing is set while walking unfinished downloads, which are expected to be incomplete
+	tolerateMissing bool
+}
+
+// Fsck - check that latest root hash of every feed has complete DAG, that stored headers and objects match their
+// hashes and that nothing is stored without being referenced. Only latest root hash of each feed is checked, since
+// data of older sequences is removed once newer one is retrieved. With repair enabled, orphans and corrupted
+// entries are removed and incomplete feeds are fetched again from the tracker. Node must not be running meanwhile.
+func (s *Service) Fsck(repair bool) (FsckReport, error) {
+	report := FsckReport{}
+	walk := &fsckWalk{
+		report:            &report,
+		reachableHeaders:  make(map[string]bool),
+		referencedObjects: make(map[string]bool),
+	}
+
+	latest, err := s.latestRootHashes()
+	if err != nil {
+		return report, err
+	}
+	report.Feeds = len(latest)
+
+	incomplete := make(map[string]model.RootHash)
+	for _, rootHash := range latest {
+		// data of evicted feed was removed on purpose when it exceeded quota
+		if feed, err := s.db.GetFeed(rootHash.Publisher); err == nil && feed.State == model.FeedEvicted {
+			continue
+		}
+		complete, err := s.fsckHeader(walk, rootHash.ObjectHeaderHash, "")
+		if err != nil {
+			return report, err
+		}
+		if !complete {
+			report.IncompleteRootHashes = append(report.IncompleteRootHashes, rootHash.Key())
+			incomplete[rootHash.Key()] = rootHash
+		}
+	}
+
+	// data of downloads that are still in progress isn't referenced by stored root hash yet, but isn't orphaned
+	downloads, err := s.db.GetUnfinishedDownloads()
+	if err != nil {
+		return report, fmt.Errorf("fetching unfinished downloads failed due to error: %v", err)
+	}
+	walk.tolerateMissing = true
+	for _, download := range downloads {
+		if _, err := s.fsckHeader(walk, download.RootHash.ObjectHeaderHash, ""); err != nil {
+			return report, err
+		}
+	}
+
+	if err := s.findOrphans(walk); err != nil {
+		return report, err
+	}
+
+	if repair {
+		if err := s.repair(&report, incomplete); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (s *Service) latestRootHashes() ([]model.RootHash, error) {
+	rootHashes, err := s.db.GetAllRootHashes()
+	if err != nil {
+		return nil, fmt.Errorf("fetching root hashes failed due to error: %v", err)
+	}
+
+	latest := make(map[string]model.RootHash)
+	for _, rootHash := range rootHashes {
+		if existing, ok := latest[rootHash.Publisher]; !ok || rootHash.Sequence > existing.Sequence {
+			latest[rootHash.Publisher] = rootHash
+		}
+	}
+
+	result := make([]model.RootHash, 0, len(latest))
+	for _, rootHash := range latest {
+		result = append(result, rootHash)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Publisher < result[j].Publisher })
+	return result, nil
+}
+
+// fsckHeader - check header with given hash and everything it references, returns false if any part is missing
+// or corrupted. Parent is empty for root header.
+func (s *Service) fsckHeader(walk *fsckWalk, hash, parent string) (bool, error) {
+	if complete, ok := walk.reachableHeaders[hash]; ok {
+		return complete, nil
+	}
+
+	header, err := s.db.GetObjectHeader(hash)
+	if err == errors.ErrCannotFindObjectHeader {
+		if !walk.tolerateMissing && parent != "" {
+			walk.report.DanglingReferences = append(walk.report.DanglingReferences,
+				fmt.Sprintf("object header: %v references missing object header: %v", parent, hash))
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("fetching object header with hash: %v failed due to error: %v", hash, err)
+	}
+	walk.reachableHeaders[hash] = false
+
+	complete := true
+	if err := parcel.CheckHash(header, hash); err != nil {
+		walk.report.CorruptedHeaders = append(walk.report.CorruptedHeaders, hash)
+		complete = false
+	}
+
+	if len(header.ObjectHash) > 0 {
+		objectComplete, err := s.fsckObject(walk, header.ObjectHash, hash)
+		if err != nil {
+			return false, err
+		}
+		complete = complete && objectComplete
+	}
+
+	for _, ref := range header.ExternalReferences {
+		refComplete, err := s.fsckHeader(walk, ref, hash)
+		if err != nil {
+			return false, err
+		}
+		complete = complete && refComplete
+	}
+	walk.reachableHeaders[hash] = complete
+	return complete, nil
+}
+
+func (s *Service) fsckObject(walk *fsckWalk, hash, headerHash string) (bool, error) {
+	if complete, ok := walk.referencedObjects[hash]; ok {
+		return complete, nil
+	}
+
+	object, err := s.db.GetObject(hash)
+	if err == errors.ErrCannotFindObject {
+		if !walk.tolerateMissing {
+			walk.report.DanglingReferences = append(walk.report.DanglingReferences,
+				fmt.Sprintf("object header: %v references missing object: %v", headerHash, hash))
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("fetching object with hash: %v failed due to error: %v", hash, err)
+	}
+	if err := parcel.CheckHash(object, hash); err != nil {
+		walk.referencedObjects[hash] = false
+		walk.report.CorruptedObjects = append(walk.report.CorruptedObjects, hash)
+		return false, nil
+	}
+	walk.referencedObjects[hash] = true
+	return true, nil
+}
+
+func (s *Service) findOrphans(walk *fsckWalk) error {
+	headerHashes, err := s.db.GetAllObjectHeaderHashes()
+	if err != nil {
+		return fmt.Errorf("fetching object header hashes failed due to error: %v", err)
+	}
+	walk.report.ObjectHeaders = len(headerHashes)
+	for _, hash := range headerHashes {
+		if _, ok := walk.reachableHeaders[hash]; !ok {
+			walk.report.OrphanedHeaders = append(walk.report.OrphanedHeaders, hash)
+		}
+	}
+
+	objectHashes, err := s.db.GetAllObjectHashes()
+	if err != nil {
+		return fmt.Errorf("fetching object hashes failed due to error: %v", err)
+	}
+	walk.report.Objects = len(objectHashes)
+	for _, hash := range objectHashes {
+		if _, ok := walk.referencedObjects[hash]; !ok {
+			walk.report.OrphanedObjects = append(walk.report.OrphanedObjects, hash)
+		}
+	}
+	return nil
+}
+
+// repair - remove orphaned and corrupted entries, then fetch incomplete feeds again. Removing corrupted entries
+// first makes them missing, so they are fetched again as well.
+func (s *Service) repair(report *FsckReport, incomplete map[string]model.RootHash) error {
+	for _, hash := range append(report.OrphanedHeaders, report.CorruptedHeaders...) {
+		if err := s.db.RemoveObjectHeader(hash); err != nil && err != errors.ErrCannotFindObjectHeader {
+			return fmt.Errorf("removing object header with hash: %v failed due to error: %v", hash, err)
+		}
+		report.RemovedHeaders = append(report.RemovedHeaders, hash)
+	}
+	for _, hash := range append(report.OrphanedObjects, report.CorruptedObjects...) {
+		if err := s.db.RemoveObject(hash); err != nil && err != errors.ErrCannotFindObject {
+			return fmt.Errorf("removing object with hash: %v failed due to error: %v", hash, err)
+		}
+		report.RemovedObjects = append(report.RemovedObjects, hash)
+	}
+
+	if len(incomplete) == 0 {
+		return nil
+	}
+
+	for key, rootHash := range incomplete {
+		if err := s.retrieveHeaders(&retrieval{client: s.client, rootHash: rootHash}, rootHash.ObjectHeaderHash); err != nil {
+			s.rootHashLogger(rootHash).WithError(err).Error("Re-fetching data failed")
+			continue
+		}
+		if err := s.checkSignature(rootHash); err != nil {
+			s.rootHashLogger(rootHash).WithError(err).Error("Re-fetched data is still not valid")
+			continue
+		}
+		report.RepairedRootHashes = append(report.RepairedRootHashes, key)
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/parcel"
+	"github.com/SkycoinProject/cxo-2/pkg/signature"
+	"github.com/SkycoinProject/cxo-2/pkg/tracker/fake"
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// newTestFeed - feed built from entry, signed by newly generated publisher
+func newTestFeed(t *testing.T, root parcel.Entry) model.PublishDataRequest {
+	t.Helper()
+	feedParcel, hash, err := parcel.Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey, secKey := cipher.GenerateKeyPair()
+	rootHash := model.RootHash{
+		Publisher:        pubKey.Hex(),
+		SignatureVersion: signature.RootHashVersion,
+		Sequence:         1,
+		Timestamp:        time.Now(),
+		ObjectHeaderHash: hash,
+	}
+	if rootHash.Signature, err = signature.SignRootHash(rootHash, pubKey, secKey); err != nil {
+		t.Fatal(err)
+	}
+	return model.PublishDataRequest{RootHash: rootHash, Parcel: feedParcel}
+}
+
+// storeTestFeed - save root hash with all of its data, the same way completed download does
+func storeTestFeed(t *testing.T, s *Service, req model.PublishDataRequest) parcel.Feed {
+	t.Helper()
+	feed := storeTestData(t, s, req)
+	if err := s.db.SaveRootHash(req.RootHash); err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
+
+// storeTestData - save object headers and objects of the feed without its root hash
+func storeTestData(t *testing.T, s *Service, req model.PublishDataRequest) parcel.Feed {
+	t.Helper()
+	feed, err := parcel.NewFeed(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for hash, header := range feed.ObjectHeaders {
+		if err := s.db.SaveObjectHeader(hash, req.RootHash, header); err != nil {
+			t.Fatal(err)
+		}
+		if len(header.ObjectHash) > 0 {
+			if err := s.db.SaveObject(header.ObjectHash, hash, feed.Objects[header.ObjectHash]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return feed
+}
+
+// testEntry - file entry with given name and content
+func testEntry(name, content string) parcel.Entry {
+	return parcel.Entry{Meta: []model.Meta{{Key: "name", Value: name}}, Data: []byte(content)}
+}
+
+// testDirectory - entry referencing its children
+func testDirectory(name string, children ...parcel.Entry) parcel.Entry {
+	return parcel.Entry{Meta: []model.Meta{{Key: "name", Value: name}}, Children: children}
+}
+
+// headerOf - hash of the feed's object header whose object is the entry's data
+func headerOf(t *testing.T, feed parcel.Feed, content string) (string, model.ObjectHeader) {
+	t.Helper()
+	for hash, header := range feed.ObjectHeaders {
+		if object, ok := feed.Objects[header.ObjectHash]; ok && string(object.Data) == content {
+			return hash, header
+		}
+	}
+	t.Fatalf("feed doesn't contain object: %v", content)
+	return "", model.ObjectHeader{}
+}
+
+func TestFsck(t *testing.T) {
+	shared := testEntry("shared", "shared content")
+
+	tests := []struct {
+		name string
+		// prepare - store feeds and damage them, returning report expected from Fsck, without counts
+		prepare func(t *testing.T, s *Service) FsckReport
+	}{
+		{
+			name: "complete feeds",
+			prepare: func(t *testing.T, s *Service) FsckReport {
+				storeTestFeed(t, s, newTestFeed(t, testDirectory("a", testEntry("file", "content"), shared)))
+				storeTestFeed(t, s, newTestFeed(t, testDirectory("b", shared)))
+				return FsckReport{}
+			},
+		},
+		{
+			name: "dangling reference to missing object",
+			prepare: func(t *testing.T, s *Service) FsckReport {
+				req := newTestFeed(t, testDirectory("a", testEntry("file", "content")))
+				feed := storeTestFeed(t, s, req)
+				hash, header := headerOf(t, feed, "content")
+				if err := s.db.RemoveObject(header.ObjectHash); err != nil {
+					t.Fatal(err)
+				}
+				return FsckReport{
+					DanglingReferences: []string{"object header: " + hash + " references missing object: " +
+						header.ObjectHash},
+					IncompleteRootHashes: []string{req.RootHash.Key()},
+				}
+			},
+		},
+		{
+			name: "dangling reference to missing object header",
+			prepare: func(t *testing.T, s *Service) FsckReport {
+				req := newTestFeed(t, testDirectory("a", testEntry("file", "content")))
+				feed := storeTestFeed(t, s, req)
+				hash, header := headerOf(t, feed, "content")
+				if err := s.db.RemoveObjectHeader(hash); err != nil {
+					t.Fatal(err)
+				}
+				return FsckReport{
+					DanglingReferences: []string{"object header: " + req.RootHash.ObjectHeaderHash +
+						" references missing object header: " + hash},
+					// object of removed header is no longer referenced
+					OrphanedObjects:      []string{header.ObjectHash},
+					IncompleteRootHashes: []string{req.RootHash.Key()},
+				}
+			},
+		},
+		{
+			name: "corrupted object header and object",
+			prepare: func(t *testing.T, s *Service) FsckReport {
+				req := newTestFeed(t, testDirectory("a", testEntry("file", "content"), testEntry("other", "other")))
+				feed := storeTestFeed(t, s, req)
+				hash, header := headerOf(t, feed, "content")
+				if err := s.db.SaveObject(header.ObjectHash, hash, model.Object{Length: 7, Data: []byte("CONTENT")}); err != nil {
+					t.Fatal(err)
+				}
+				otherHash, otherHeader := headerOf(t, feed, "other")
+				otherHeader.Meta = []model.Meta{{Key: "name", Value: "changed"}}
+				if err := s.db.SaveObjectHeader(otherHash, req.RootHash, otherHeader); err != nil {
+					t.Fatal(err)
+				}
+				return FsckReport{
+					CorruptedHeaders:     []string{otherHash},
+					CorruptedObjects:     []string{header.ObjectHash},
+					IncompleteRootHashes: []string{req.RootHash.Key()},
+				}
+			},
+		},
+		{
+			name: "orphaned object header and object",
+			prepare: func(t *testing.T, s *Service) FsckReport {
+				storeTestFeed(t, s, newTestFeed(t, testDirectory("a", testEntry("file", "content"))))
+				// data of the feed is stored, but its root hash isn't
+				feed := storeTestData(t, s, newTestFeed(t, testEntry("orphan", "orphaned content")))
+				hash, header := headerOf(t, feed, "orphaned content")
+				return FsckReport{OrphanedHeaders: []string{hash}, OrphanedObjects: []string{header.ObjectHash}}
+			},
+		},
+		{
+			name: "incomplete data shared by several feeds",
+			prepare: func(t *testing.T, s *Service) FsckReport {
+				a := newTestFeed(t, testDirectory("a", testEntry("file", "content"), shared))
+				b := newTestFeed(t, testDirectory("b", shared))
+				feed := storeTestFeed(t, s, a)
+				storeTestFeed(t, s, b)
+				hash, header := headerOf(t, feed, "shared content")
+				if err := s.db.RemoveObject(header.ObjectHash); err != nil {
+					t.Fatal(err)
+				}
+				return FsckReport{
+					DanglingReferences: []string{"object header: " + hash + " references missing object: " +
+						header.ObjectHash},
+					IncompleteRootHashes: []string{a.RootHash.Key(), b.RootHash.Key()},
+				}
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestService(t, config.Config{})
+			expected := tc.prepare(t, s)
+			report, err := s.Fsck(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkFsckReport(t, report, expected)
+			if report.HasProblems() != expected.HasProblems() {
+				t.Errorf("unexpected result of HasProblems: %v", report.HasProblems())
+			}
+		})
+	}
+}
+
+func TestFsckRepair(t *testing.T) {
+	tracker := fake.New(http.DefaultClient)
+	server := httptest.NewServer(tracker)
+	defer server.Close()
+
+	req := newTestFeed(t, testDirectory("a", testEntry("file", "content"), testEntry("other", "other")))
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(server.URL+"/data", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("publishing feed returned status: %v", resp.Status)
+	}
+
+	s := newTestService(t, config.Config{Trackers: []config.TrackerConfig{{Address: server.URL}}})
+	feed := storeTestFeed(t, s, req)
+	hash, header := headerOf(t, feed, "content")
+	if err := s.db.SaveObject(header.ObjectHash, hash, model.Object{Length: 7, Data: []byte("CONTENT")}); err != nil {
+		t.Fatal(err)
+	}
+	otherHash, otherHeader := headerOf(t, feed, "other")
+	if err := s.db.RemoveObjectHeader(otherHash); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := s.Fsck(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// corrupted object is removed together with object orphaned by removed header
+	removed := []string{header.ObjectHash, otherHeader.ObjectHash}
+	if !equalStrings(report.RemovedObjects, removed) {
+		t.Errorf("expected removed objects: %v, got: %v", removed, report.RemovedObjects)
+	}
+	if !equalStrings(report.RepairedRootHashes, []string{req.RootHash.Key()}) {
+		t.Errorf("expected repaired root hashes: %v, got: %v", []string{req.RootHash.Key()}, report.RepairedRootHashes)
+	}
+
+	report, err = s.Fsck(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.HasProblems() {
+		t.Errorf("expected repaired data to be complete, got: %+v", report)
+	}
+}
+
+func checkFsckReport(t *testing.T, report, expected FsckReport) {
+	t.Helper()
+	fields := []struct {
+		name             string
+		actual, expected []string
+	}{
+		{"dangling references", report.DanglingReferences, expected.DanglingReferences},
+		{"corrupted object headers", report.CorruptedHeaders, expected.CorruptedHeaders},
+		{"corrupted objects", report.CorruptedObjects, expected.CorruptedObjects},
+		{"orphaned object headers", report.OrphanedHeaders, expected.OrphanedHeaders},
+		{"orphaned objects", report.OrphanedObjects, expected.OrphanedObjects},
+		{"incomplete root hashes", report.IncompleteRootHashes, expected.IncompleteRootHashes},
+	}
+	for _, f := range fields {
+		if !equalStrings(f.actual, f.expected) {
+			t.Errorf("expected %v: %v, got: %v", f.name, f.expected, f.actual)
+		}
+	}
+}
+
+// equalStrings - whether slices contain the same strings regardless of order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,51 +1,90 @@
 package node
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/SkycoinProject/cxo-2/pkg/errors"
 	"github.com/SkycoinProject/cxo-2/pkg/model"
 	"github.com/SkycoinProject/cxo-2/pkg/node/data"
 	"github.com/gin-gonic/gin"
-)
-
-const (
-	host = "127.0.0.1"
-	port = 6421
+	log "github.com/sirupsen/logrus"
 )
 
 type WebServer struct {
 	Engine *gin.Engine
+	server *http.Server
 }
 
 type Controller struct {
-	Data data.Data
+	Data    data.Data
+	Service *Service
 }
 
 type ErrorResponse struct {
 	Error string `json:"message"`
 }
 
-func InitServerAndController(data data.Data) *WebServer {
+func InitServerAndController(service *Service) *WebServer {
 	server := &WebServer{
-		Engine: gin.Default(),
+		Engine: gin.New(),
 	}
+	server.Engine.Use(requestLogger(service.logger), gin.Recovery())
+	server.server = &http.Server{Addr: service.config.APIAddress, Handler: server.Engine}
 
-	ctrl := &Controller{Data: data}
+	ctrl := &Controller{Data: service.db, Service: service}
 	server.initRoutes(ctrl)
 	return server
 }
 
-func (s *WebServer) Run() {
-	if err := s.Engine.Run(fmt.Sprintf("%s:%v", host, port)); err != nil {
-		panic(err.Error())
+// Run - serve local API until server is shut down
+func (s *WebServer) Run() error {
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown - stop accepting requests and wait for ones in progress
+func (s *WebServer) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// requestLogger - log handled requests to the node's logger, instead of stdout where gin logs them by default
+func requestLogger(logger log.FieldLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		logger.WithFields(log.Fields{
+			"method":  c.Request.Method,
+			"path":    c.Request.URL.Path,
+			"status":  c.Writer.Status(),
+			"latency": time.Since(start),
+		}).Debug("Local API request handled")
 	}
 }
 
 func (s *WebServer) initRoutes(ctrl *Controller) {
+	s.Engine.GET("/healthz", ctrl.health)
+	s.Engine.GET("/readyz", ctrl.readiness)
+	s.Engine.GET("/metrics", gin.WrapH(ctrl.Service.metrics.registry))
+
 	public := s.Engine.Group("/api/v1")
+	public.GET("/status", ctrl.getStatus)
 	public.POST("/registerApp", ctrl.registerApp)
+	public.GET("/feeds", ctrl.getFeeds)
+	public.POST("/feeds/:publisher/resume", ctrl.resumeFeed)
+	public.GET("/feeds/:publisher/export", ctrl.exportFeed)
+	public.POST("/import", ctrl.importFeed)
+	public.GET("/subscriptions", ctrl.getSubscriptions)
+	public.POST("/subscriptions/:publisher", ctrl.subscribe)
+	public.DELETE("/subscriptions/:publisher", ctrl.unsubscribe)
+	public.GET("/blocked", ctrl.getBlockedPublishers)
+	public.POST("/blocked/:publisher", ctrl.block)
+	public.DELETE("/blocked/:publisher", ctrl.unblock)
 }
 
 func (ctrl *Controller) registerApp(c *gin.Context) {
@@ -62,3 +101,167 @@ func (ctrl *Controller) registerApp(c *gin.Context) {
 
 	c.Writer.WriteHeader(http.StatusCreated)
 }
+
+func (ctrl *Controller) health(c *gin.Context) {
+	writeHealth(c, ctrl.Service.Health())
+}
+
+func (ctrl *Controller) readiness(c *gin.Context) {
+	writeHealth(c, ctrl.Service.Readiness())
+}
+
+func writeHealth(c *gin.Context, health model.Health) {
+	status := http.StatusOK
+	if health.Status != model.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, health)
+}
+
+func (ctrl *Controller) getStatus(c *gin.Context) {
+	status, err := ctrl.Service.Status()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (ctrl *Controller) getFeeds(c *gin.Context) {
+	feeds, err := ctrl.Service.FeedStatuses()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, feeds)
+}
+
+func (ctrl *Controller) resumeFeed(c *gin.Context) {
+	err := ctrl.Service.ResumeFeed(c.Param("publisher"))
+	if err == errors.ErrCannotFindFeed {
+		c.AbortWithStatusJSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+func (ctrl *Controller) exportFeed(c *gin.Context) {
+	var sequence *uint64
+	if param, ok := c.GetQuery("sequence"); ok {
+		seq, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, ErrorResponse{Error: errors.ErrUnableToProcessRequest.Error()})
+			return
+		}
+		sequence = &seq
+	}
+
+	rootHash, err := ctrl.Service.ExportedRootHash(c.Param("publisher"), sequence)
+	if err == errors.ErrCannotFindRootHash {
+		c.AbortWithStatusJSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-tar")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", rootHash.Key()+".tar"))
+	c.Status(http.StatusOK)
+	// response is already started, so failure can only be logged and archive is left unfinished
+	if err := ctrl.Service.ExportArchive(c.Writer, rootHash); err != nil {
+		ctrl.Service.rootHashLogger(rootHash).WithError(err).Error("Exporting feed failed")
+	}
+}
+
+func (ctrl *Controller) importFeed(c *gin.Context) {
+	rootHash, err := ctrl.Service.ImportArchive(c.Request.Body)
+	if err != nil {
+		ctrl.Service.rootHashLogger(rootHash).WithError(err).Error("Importing feed failed")
+		c.AbortWithStatusJSON(importErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rootHash)
+}
+
+func importErrorStatus(err error) int {
+	switch err {
+	case errors.ErrStaleSequence, errors.ErrForkedSequence, errors.ErrTimestampInFuture, errors.ErrPublisherBlocked,
+		errors.ErrLegacySignature:
+		return validationErrorStatus(err)
+	case errors.ErrFeedPaused, errors.ErrFeedEvicted:
+		return http.StatusConflict
+	case errors.ErrQuotaExceeded:
+		return http.StatusInsufficientStorage
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
+
+func (ctrl *Controller) getSubscriptions(c *gin.Context) {
+	subscriptions, err := ctrl.Service.Subscriptions()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+func (ctrl *Controller) subscribe(c *gin.Context) {
+	ctrl.updateTrust(c, ctrl.Service.Subscribe)
+}
+
+func (ctrl *Controller) unsubscribe(c *gin.Context) {
+	ctrl.updateTrust(c, ctrl.Service.Unsubscribe)
+}
+
+func (ctrl *Controller) getBlockedPublishers(c *gin.Context) {
+	blocked, err := ctrl.Service.BlockedPublishers()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, blocked)
+}
+
+func (ctrl *Controller) block(c *gin.Context) {
+	ctrl.updateTrust(c, ctrl.Service.Block)
+}
+
+func (ctrl *Controller) unblock(c *gin.Context) {
+	ctrl.updateTrust(c, ctrl.Service.Unblock)
+}
+
+// updateTrust - apply change of subscriptions or block list to the publisher from request path
+func (ctrl *Controller) updateTrust(c *gin.Context, update func(publisher string) error) {
+	if err := update(c.Param("publisher")); err != nil {
+		c.AbortWithStatusJSON(trustErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+func trustErrorStatus(err error) int {
+	switch err {
+	case errors.ErrInvalidPublicKey:
+		return http.StatusUnprocessableEntity
+	case errors.ErrCannotFindSubscription, errors.ErrCannotFindBlocked:
+		return http.StatusNotFound
+	case errors.ErrConfiguredPublisher:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,97 @@
+package node
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/transport"
+)
+
+// shutdownTimeout - how long node waits for downloads in progress to finish before interrupting them
+const shutdownTimeout = 30 * time.Second
+
+// inFlight - requests from trackers and other nodes that are being handled
+type inFlight struct {
+	mux     sync.Mutex
+	closed  bool
+	running sync.WaitGroup
+}
+
+// track - count requests handled by handler, refusing new ones once node is shutting down
+func (f *inFlight) track(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mux.Lock()
+		if f.closed {
+			f.mux.Unlock()
+			http.Error(w, "node is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		f.running.Add(1)
+		f.mux.Unlock()
+
+		defer f.running.Done()
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (f *inFlight) isClosed() bool {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.closed
+}
+
+func (f *inFlight) close() {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.closed = true
+}
+
+// shutdown - stop accepting notifications and data requests, let the ones in progress and downloads that already
+// started finish, and stop local API. Downloads still running after shutdownTimeout are interrupted between
+// objects, they stay unfinished and are resumed on next start together with ones that didn't start yet.
+func (s *Service) shutdown(webServer *WebServer, server transport.Server) {
+	s.logger.Info("Stopping cxo node")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// notifications in progress are finished, so root hashes acknowledged to trackers are persisted as pending
+	s.requests.close()
+	if !waitUntil(ctx, s.requests.running.Wait) {
+		s.logger.Warn("Requests in progress didn't finish before shutdown timeout")
+	}
+	if err := server.Close(); err != nil {
+		s.logger.WithError(err).Error("Closing node server failed")
+	}
+
+	s.queue.close()
+	if !waitUntil(ctx, s.queue.wait) {
+		s.logger.Warnf("Downloads didn't finish within %v, interrupting them", shutdownTimeout)
+		s.interruptDownloads()
+		s.queue.wait()
+	}
+
+	// local API is stopped last, so node can still be queried while it's shutting down
+	apiCtx, apiCancel := context.WithTimeout(context.Background(), time.Second)
+	defer apiCancel()
+	if err := webServer.Shutdown(apiCtx); err != nil {
+		s.logger.WithError(err).Error("Stopping local API failed")
+	}
+	s.logger.Info("Cxo node stopped")
+}
+
+// waitUntil - call wait and return true if it returns before context is done
+func waitUntil(ctx context.Context, wait func()) bool {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
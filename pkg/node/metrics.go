@@ -0,0 +1,87 @@
+package node
+
+import (
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/metrics"
+)
+
+// results of root hash notifications received from trackers
+const (
+	notificationAccepted  = "accepted"
+	notificationDuplicate = "duplicate"
+	notificationRefused   = "refused"
+	notificationInvalid   = "invalid"
+	notificationFailed    = "failed"
+)
+
+// kinds of data fetched from trackers and peers
+const (
+	fetchObjectHeaders = "object_headers"
+	fetchObject        = "object"
+)
+
+// results of notifying registered apps about new data
+const (
+	appNotified     = "success"
+	appNotifyFailed = "failure"
+)
+
+// nodeMetrics - metrics of node exposed to Prometheus. Each service has its own registry, so that several nodes
+// running in one process, e.g. in end-to-end tests, don't share them.
+type nodeMetrics struct {
+	registry              *metrics.Registry
+	notificationsReceived *metrics.Counter
+	headersFetched        *metrics.Counter
+	objectsFetched        *metrics.Counter
+	bytesDownloaded       *metrics.Counter
+	fetchDuration         *metrics.Histogram
+	signatureFailures     *metrics.Counter
+	gcReclaimedBytes      *metrics.Counter
+	appNotifications      *metrics.Counter
+}
+
+func newNodeMetrics(s *Service) *nodeMetrics {
+	r := metrics.NewRegistry()
+	m := &nodeMetrics{
+		registry: r,
+		notificationsReceived: r.Counter("cxo_node_notifications_received_total",
+			"Root hash notifications received from trackers by result.", "result"),
+		headersFetched: r.Counter("cxo_node_object_headers_fetched_total",
+			"Object headers fetched from trackers and peers."),
+		objectsFetched: r.Counter("cxo_node_objects_fetched_total",
+			"Objects fetched from trackers and peers."),
+		bytesDownloaded: r.Counter("cxo_node_downloaded_bytes_total",
+			"Bytes of objects fetched from trackers and peers."),
+		fetchDuration: r.Histogram("cxo_node_fetch_duration_seconds",
+			"Duration of successful fetches of object headers and objects, including failover to other sources.",
+			metrics.DefaultBuckets, "kind"),
+		signatureFailures: r.Counter("cxo_node_signature_failures_total",
+			"Retrieved sequences whose signature verification failed."),
+		gcReclaimedBytes: r.Counter("cxo_node_gc_reclaimed_bytes_total",
+			"Bytes of objects removed because they are no longer referenced or their feed was evicted."),
+		appNotifications: r.Counter("cxo_node_app_notifications_total",
+			"Notifications of registered apps about new data by result.", "result"),
+	}
+	r.GaugeFunc("cxo_node_database_size_bytes", "Size of node data store.", func() float64 {
+		size, err := s.db.Size()
+		if err != nil {
+			s.logger.WithError(err).Error("Fetching size of data store failed")
+		}
+		return float64(size)
+	})
+	r.GaugeFunc("cxo_node_stored_bytes", "Size of latest sequences of stored feeds declared by their root headers.",
+		func() float64 {
+			size, err := s.storedSize("")
+			if err != nil {
+				s.logger.WithError(err).Error("Computing size of stored feeds failed")
+			}
+			return float64(size)
+		})
+	return m
+}
+
+// observeFetch - record duration of fetch that started at given time
+func (m *nodeMetrics) observeFetch(kind string, start time.Time) {
+	m.fetchDuration.Observe(time.Since(start).Seconds(), kind)
+}
@@ -0,0 +1,206 @@
+package node
+
+import (
+	"sync"
+
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+)
+
+// feedQueue - serializes processing of root hashes per publisher. Only the newest waiting root hash is kept for
+// each publisher, so bursts of notifications are coalesced and sequences lower than already accepted are dropped.
+// Sequence is accepted only once processing stores its root hash, so root hash that turns out to be invalid
+// doesn't make the queue drop valid ones.
+type feedQueue struct {
+	mux     sync.Mutex
+	feeds   map[string]*feedState
+	process func(rootHash model.RootHash) bool
+	discard func(rootHash model.RootHash, reason string)
+	closed  bool
+	running sync.WaitGroup
+}
+
+type feedState struct {
+	pending *model.RootHash
+	// processing - key of root hash being processed, its download status must not be changed by discarding
+	processing string
+	// lastSequence - sequence of the latest root hash stored by processing, if any is accepted
+	lastSequence uint64
+	accepted     bool
+	running      bool
+}
+
+// droppedRootHash - root hash removed from the queue without processing
+type droppedRootHash struct {
+	rootHash model.RootHash
+	reason   string
+}
+
+// newFeedQueue - queue that processes root hashes with the function, which returns whether root hash is stored
+func newFeedQueue(process func(model.RootHash) bool, discard func(model.RootHash, string)) *feedQueue {
+	return &feedQueue{
+		feeds:   make(map[string]*feedState),
+		process: process,
+		discard: discard,
+	}
+}
+
+// push - add root hash to its publisher's queue and start processing it if publisher is idle.
+// Returns false if root hash is dropped because newer sequence is already accepted or waiting, it's already being
+// processed or queue is closed.
+func (q *feedQueue) push(rootHash model.RootHash) bool {
+	q.mux.Lock()
+	// download of the root hash stays pending, so it's resumed on next start
+	if q.closed {
+		q.mux.Unlock()
+		return false
+	}
+
+	accepted, drops := q.enqueue(rootHash)
+	// discarding is waited for together with processing, so download status isn't saved after queue is closed
+	if len(drops) > 0 {
+		q.running.Add(1)
+	}
+	q.mux.Unlock()
+
+	for _, d := range drops {
+		q.discard(d.rootHash, d.reason)
+	}
+	if len(drops) > 0 {
+		q.running.Done()
+	}
+	return accepted
+}
+
+// enqueue - make root hash the pending one of its publisher unless newer sequence is already accepted or waiting.
+// Root hashes dropped from the queue are returned, root hash that is being processed is never dropped, so its
+// download isn't marked as failed. Queue has to be locked.
+func (q *feedQueue) enqueue(rootHash model.RootHash) (bool, []droppedRootHash) {
+	feed, ok := q.feeds[rootHash.Publisher]
+	if !ok {
+		feed = &feedState{}
+		q.feeds[rootHash.Publisher] = feed
+	}
+
+	if rootHash.Key() == feed.processing {
+		return false, nil
+	}
+	if feed.accepted && rootHash.Sequence < feed.lastSequence {
+		return false, []droppedRootHash{{rootHash: rootHash, reason: "newer sequence is already accepted"}}
+	}
+
+	var drops []droppedRootHash
+	if feed.pending != nil {
+		switch {
+		case feed.pending.Key() == rootHash.Key():
+			// the same root hash pushed again just stays pending
+			return true, nil
+		case rootHash.Sequence < feed.pending.Sequence:
+			return false, []droppedRootHash{{rootHash: rootHash, reason: "newer sequence is already waiting"}}
+		default:
+			drops = append(drops, droppedRootHash{rootHash: *feed.pending,
+				reason: "superseded by newer sequence before processing started"})
+		}
+	}
+	feed.pending = &rootHash
+
+	if !feed.running {
+		feed.running = true
+		q.running.Add(1)
+		go q.run(feed)
+	}
+	return true, drops
+}
+
+func (q *feedQueue) run(feed *feedState) {
+	for {
+		q.mux.Lock()
+		feed.processing = ""
+		if feed.pending == nil || q.closed {
+			feed.running = false
+			q.mux.Unlock()
+			q.running.Done()
+			return
+		}
+		rootHash := *feed.pending
+		feed.pending = nil
+		feed.processing = rootHash.Key()
+		q.mux.Unlock()
+
+		if !q.process(rootHash) {
+			continue
+		}
+		q.mux.Lock()
+		if !feed.accepted || rootHash.Sequence > feed.lastSequence {
+			feed.lastSequence = rootHash.Sequence
+			feed.accepted = true
+		}
+		q.mux.Unlock()
+	}
+}
+
+// close - stop processing root hashes, ones that are still waiting stay pending and are resumed on next start
+func (q *feedQueue) close() {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.closed = true
+}
+
+// wait - wait until root hashes that are being processed are done
+func (q *feedQueue) wait() {
+	q.running.Wait()
+}
+
+// status - number of feeds whose root hash is being processed and of root hashes waiting for them
+func (q *feedQueue) status() model.QueueStatus {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	var status model.QueueStatus
+	for _, feed := range q.feeds {
+		if feed.running {
+			status.Running++
+		}
+		if feed.pending != nil {
+			status.Waiting++
+		}
+	}
+	return status
+}
+
+// feedLocks - locks of publishers' feeds, so that stored data of a feed is changed by one download or import at
+// a time. Otherwise removing data of the previous sequence would remove data the other one already stored.
+type feedLocks struct {
+	mux   sync.Mutex
+	feeds map[string]*feedLock
+}
+
+type feedLock struct {
+	sync.Mutex
+	users int
+}
+
+func newFeedLocks() *feedLocks {
+	return &feedLocks{feeds: make(map[string]*feedLock)}
+}
+
+// lock - wait until feed of the publisher is unlocked and lock it, returned function unlocks it
+func (l *feedLocks) lock(publisher string) func() {
+	l.mux.Lock()
+	feed, ok := l.feeds[publisher]
+	if !ok {
+		feed = &feedLock{}
+		l.feeds[publisher] = feed
+	}
+	feed.users++
+	l.mux.Unlock()
+
+	feed.Lock()
+	return func() {
+		feed.Unlock()
+		l.mux.Lock()
+		defer l.mux.Unlock()
+		feed.users--
+		if feed.users == 0 {
+			delete(l.feeds, publisher)
+		}
+	}
+}
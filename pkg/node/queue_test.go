@@ -0,0 +1,151 @@
+package node
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+)
+
+// testQueue - queue whose processing blocks until released and stores root hashes of sequences listed as valid
+type testQueue struct {
+	*feedQueue
+	mux       sync.Mutex
+	processed []uint64
+	dropped   []uint64
+	started   chan struct{}
+	release   chan struct{}
+}
+
+func newTestQueue(valid ...uint64) *testQueue {
+	tq := &testQueue{started: make(chan struct{}, 10), release: make(chan struct{})}
+	tq.feedQueue = newFeedQueue(func(rootHash model.RootHash) bool {
+		tq.started <- struct{}{}
+		<-tq.release
+		tq.mux.Lock()
+		defer tq.mux.Unlock()
+		tq.processed = append(tq.processed, rootHash.Sequence)
+		for _, sequence := range valid {
+			if sequence == rootHash.Sequence {
+				return true
+			}
+		}
+		return false
+	}, func(rootHash model.RootHash, _ string) {
+		tq.mux.Lock()
+		defer tq.mux.Unlock()
+		tq.dropped = append(tq.dropped, rootHash.Sequence)
+	})
+	return tq
+}
+
+// pushProcessed - push root hash and wait until its processing starts
+func (tq *testQueue) pushProcessed(sequence uint64) {
+	tq.push(testRootHash(sequence))
+	<-tq.started
+}
+
+// process - let queue process given number of root hashes and wait until it's idle
+func (tq *testQueue) process(n int) {
+	for i := 0; i < n; i++ {
+		tq.release <- struct{}{}
+	}
+	tq.wait()
+}
+
+func testRootHash(sequence uint64) model.RootHash {
+	return model.RootHash{Publisher: "publisher", Sequence: sequence}
+}
+
+func TestFeedQueue(t *testing.T) {
+	tests := []struct {
+		name      string
+		valid     []uint64
+		run       func(tq *testQueue)
+		processed []uint64
+		dropped   []uint64
+	}{
+		{
+			name:  "waiting root hash is superseded by newer sequence",
+			valid: []uint64{1, 3},
+			run: func(tq *testQueue) {
+				tq.pushProcessed(1)
+				tq.push(testRootHash(2))
+				tq.push(testRootHash(3))
+				tq.process(2)
+			},
+			processed: []uint64{1, 3},
+			dropped:   []uint64{2},
+		},
+		{
+			name:  "lower sequence than the waiting one is dropped",
+			valid: []uint64{1, 3},
+			run: func(tq *testQueue) {
+				tq.pushProcessed(1)
+				tq.push(testRootHash(3))
+				tq.push(testRootHash(2))
+				tq.process(2)
+			},
+			processed: []uint64{1, 3},
+			dropped:   []uint64{2},
+		},
+		{
+			name:  "lower sequence than the accepted one is dropped",
+			valid: []uint64{3},
+			run: func(tq *testQueue) {
+				tq.push(testRootHash(3))
+				tq.process(1)
+				tq.push(testRootHash(2))
+			},
+			processed: []uint64{3},
+			dropped:   []uint64{2},
+		},
+		{
+			name:  "root hash being processed is not dropped when pushed again",
+			valid: []uint64{1},
+			run: func(tq *testQueue) {
+				tq.pushProcessed(1)
+				tq.push(testRootHash(1))
+				tq.process(1)
+			},
+			processed: []uint64{1},
+		},
+		{
+			name:  "invalid root hash doesn't make queue drop lower sequences",
+			valid: []uint64{2},
+			run: func(tq *testQueue) {
+				tq.push(testRootHash(100))
+				tq.process(1)
+				tq.push(testRootHash(2))
+				tq.process(1)
+			},
+			processed: []uint64{100, 2},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tq := newTestQueue(tc.valid...)
+			tc.run(tq)
+			tq.mux.Lock()
+			defer tq.mux.Unlock()
+			if !equalSequences(tq.processed, tc.processed) {
+				t.Errorf("expected processed sequences: %v, got: %v", tc.processed, tq.processed)
+			}
+			if !equalSequences(tq.dropped, tc.dropped) {
+				t.Errorf("expected dropped sequences: %v, got: %v", tc.dropped, tq.dropped)
+			}
+		})
+	}
+}
+
+func equalSequences(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,227 @@
+package node
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/errors"
+	"github.com/SkycoinProject/cxo-2/pkg/logging"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/parcel"
+)
+
+// admitRootHash - check that data of root hash with given declared size fits into feed and total quota. Sequence
+// that doesn't fit is refused according to the configured policy, sequence that fits makes the feed active again.
+func (s *Service) admitRootHash(rootHash model.RootHash, size uint64) error {
+	quota := s.config.Quota
+	var reason string
+	if limit := quota.FeedLimit(rootHash.Publisher); limit > 0 && size > limit {
+		reason = fmt.Sprintf("sequence %v has %v bytes, feed quota is %v bytes", rootHash.Sequence, size, limit)
+	} else if quota.TotalBytes > 0 {
+		// data of the feed's previous sequence is replaced, so only other feeds are counted
+		used, err := s.storedSize(rootHash.Publisher)
+		if err != nil {
+			return err
+		}
+		if used+size > quota.TotalBytes {
+			reason = fmt.Sprintf("sequence %v has %v bytes, other feeds use %v of %v bytes of total quota",
+				rootHash.Sequence, size, used, quota.TotalBytes)
+		}
+	}
+
+	if reason == "" {
+		feed, err := s.db.GetFeed(rootHash.Publisher)
+		if err == nil && feed.State != model.FeedActive {
+			s.rootHashLogger(rootHash).Info("Feed fits into quota again")
+			return s.saveFeed(model.Feed{Publisher: rootHash.Publisher, State: model.FeedActive})
+		}
+		if err != nil && err != errors.ErrCannotFindFeed {
+			return fmt.Errorf("fetching feed of publisher: %v failed due to error: %v", rootHash.Publisher, err)
+		}
+		return nil
+	}
+
+	if err := s.refuseRootHash(rootHash, reason); err != nil {
+		return err
+	}
+	return errors.ErrQuotaExceeded
+}
+
+// downloadLimit - number of object bytes download of admitted root hash can retrieve, which is the smallest of its
+// declared size, feed quota and total quota left by other feeds. Zero means no limit.
+func (s *Service) downloadLimit(rootHash model.RootHash, size uint64) (uint64, error) {
+	limit := size
+	lower := func(l uint64) {
+		if l > 0 && (limit == 0 || l < limit) {
+			limit = l
+		}
+	}
+	lower(s.config.Quota.FeedLimit(rootHash.Publisher))
+	if total := s.config.Quota.TotalBytes; total > 0 {
+		used, err := s.storedSize(rootHash.Publisher)
+		if err != nil {
+			return 0, err
+		}
+		// zero would mean no limit, and nothing can be stored anyway
+		if used >= total {
+			return 0, errors.ErrQuotaExceeded
+		}
+		lower(total - used)
+	}
+	return limit, nil
+}
+
+// refuseRootHash - apply quota policy to the feed of root hash that exceeds quota
+func (s *Service) refuseRootHash(rootHash model.RootHash, reason string) error {
+	feed := model.Feed{
+		Publisher:       rootHash.Publisher,
+		Reason:          reason,
+		RefusedRootHash: &rootHash,
+	}
+
+	switch s.config.Quota.Policy {
+	case config.RejectPolicy:
+		reclaimed, err := s.db.RemoveFeedData(rootHash.Publisher)
+		if err != nil {
+			return fmt.Errorf("removing data of publisher: %v failed due to error: %v", rootHash.Publisher, err)
+		}
+		s.metrics.gcReclaimedBytes.Add(float64(reclaimed))
+		feed.State = model.FeedEvicted
+	case config.PausePolicy:
+		feed.State = model.FeedPaused
+	default:
+		feed.State = model.FeedOverQuota
+	}
+
+	s.rootHashLogger(rootHash).Warnf("Feed is %v: %v", feed.State, reason)
+	return s.saveFeed(feed)
+}
+
+// storedSize - declared size of latest sequences of all feeds except the publisher's one
+func (s *Service) storedSize(exceptPublisher string) (uint64, error) {
+	latest, err := s.latestRootHashes()
+	if err != nil {
+		return 0, err
+	}
+
+	var size uint64
+	for _, rootHash := range latest {
+		if rootHash.Publisher == exceptPublisher {
+			continue
+		}
+		size += s.rootHashSize(rootHash)
+	}
+	return size, nil
+}
+
+// rootHashSize - size declared by root header of root hash, 0 if its data isn't stored
+func (s *Service) rootHashSize(rootHash model.RootHash) uint64 {
+	header, err := s.db.GetObjectHeader(rootHash.ObjectHeaderHash)
+	if err != nil {
+		return 0
+	}
+	return parcel.TotalSize(header)
+}
+
+// checkFeedPaused - refuse root hash of paused feed, remembering the latest one so it can be retrieved on resume
+func (s *Service) checkFeedPaused(rootHash model.RootHash) error {
+	return s.checkFeedRefusing(rootHash, model.FeedPaused)
+}
+
+// checkFeedRefusing - refuse root hash of feed in one of given states, remembering the latest one so it can be
+// retrieved on resume
+func (s *Service) checkFeedRefusing(rootHash model.RootHash, states ...model.FeedState) error {
+	feed, err := s.db.GetFeed(rootHash.Publisher)
+	if err == errors.ErrCannotFindFeed {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("fetching feed of publisher: %v failed due to error: %v", rootHash.Publisher, err)
+	}
+	refused := false
+	for _, state := range states {
+		refused = refused || feed.State == state
+	}
+	if !refused {
+		return nil
+	}
+
+	if feed.RefusedRootHash == nil || rootHash.Sequence > feed.RefusedRootHash.Sequence {
+		feed.RefusedRootHash = &rootHash
+		if err := s.saveFeed(feed); err != nil {
+			s.rootHashLogger(rootHash).WithError(err).Error("Saving feed failed")
+		}
+	}
+	if feed.State == model.FeedEvicted {
+		return errors.ErrFeedEvicted
+	}
+	return errors.ErrFeedPaused
+}
+
+// ResumeFeed - make feed active again and retrieve the latest refused sequence, which is refused again if it
+// still doesn't fit into quota
+func (s *Service) ResumeFeed(publisher string) error {
+	feed, err := s.db.GetFeed(publisher)
+	if err != nil {
+		return err
+	}
+	if err := s.saveFeed(model.Feed{Publisher: publisher, State: model.FeedActive}); err != nil {
+		return fmt.Errorf("saving feed of publisher: %v failed due to error: %v", publisher, err)
+	}
+	s.logger.WithField(logging.PublisherField, publisher).Info("Feed is resumed")
+
+	if feed.RefusedRootHash == nil {
+		return nil
+	}
+	rootHash := *feed.RefusedRootHash
+	if err := s.validateRootHash(rootHash); err != nil {
+		s.rootHashLogger(rootHash).WithError(err).Info("Refused root hash is not retrieved")
+		return nil
+	}
+	if err := s.saveDownloadStatus(rootHash, model.DownloadPending, nil); err != nil {
+		return fmt.Errorf("saving download of root hash with key: %v failed due to error: %v", rootHash.Key(), err)
+	}
+	s.queue.push(rootHash)
+	return nil
+}
+
+// FeedStatuses - state, latest sequence and its declared size of every feed known to the node
+func (s *Service) FeedStatuses() ([]model.FeedStatus, error) {
+	latest, err := s.latestRootHashes()
+	if err != nil {
+		return nil, err
+	}
+	feeds, err := s.db.GetAllFeeds()
+	if err != nil {
+		return nil, fmt.Errorf("fetching feeds failed due to error: %v", err)
+	}
+
+	statuses := make(map[string]model.FeedStatus)
+	for _, rootHash := range latest {
+		statuses[rootHash.Publisher] = model.FeedStatus{
+			Feed:     model.Feed{Publisher: rootHash.Publisher, State: model.FeedActive},
+			Sequence: rootHash.Sequence,
+			Size:     s.rootHashSize(rootHash),
+		}
+	}
+	// feed can be refused before any of its sequences is stored
+	for _, feed := range feeds {
+		status := statuses[feed.Publisher]
+		status.Feed = feed
+		statuses[feed.Publisher] = status
+	}
+
+	result := make([]model.FeedStatus, 0, len(statuses))
+	for _, status := range statuses {
+		result = append(result, status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Publisher < result[j].Publisher })
+	return result, nil
+}
+
+func (s *Service) saveFeed(feed model.Feed) error {
+	feed.UpdatedAt = time.Now()
+	return s.db.SaveFeed(feed)
+}
@@ -0,0 +1,54 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/errors"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+)
+
+func TestDownloadLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		quota    config.QuotaConfig
+		declared uint64
+		limit    uint64
+		err      error
+	}{
+		{name: "no quota", declared: 100, limit: 100},
+		{name: "undeclared size without quota"},
+		{name: "declared size below quotas", quota: config.QuotaConfig{FeedBytes: 500, TotalBytes: 1000},
+			declared: 100, limit: 100},
+		{name: "undeclared size limited by feed quota", quota: config.QuotaConfig{FeedBytes: 500}, limit: 500},
+		// other feed stores 600 bytes
+		{name: "undeclared size limited by remaining total quota", quota: config.QuotaConfig{TotalBytes: 1000},
+			limit: 400},
+		{name: "feed quota lower than remaining total quota",
+			quota: config.QuotaConfig{FeedBytes: 300, TotalBytes: 1000}, limit: 300},
+		{name: "remaining total quota lower than feed quota",
+			quota: config.QuotaConfig{FeedBytes: 500, TotalBytes: 1000}, limit: 400},
+		{name: "total quota used by other feeds", quota: config.QuotaConfig{TotalBytes: 600},
+			err: errors.ErrQuotaExceeded},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestService(t, config.Config{Quota: tc.quota})
+			other := model.RootHash{Publisher: "other", Sequence: 1, ObjectHeaderHash: "otherHeader"}
+			if err := s.db.SaveObjectHeader(other.ObjectHeaderHash, other, model.ObjectHeader{Size: 600}); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.db.SaveRootHash(other); err != nil {
+				t.Fatal(err)
+			}
+
+			limit, err := s.downloadLimit(model.RootHash{Publisher: "publisher", Sequence: 1}, tc.declared)
+			if err != tc.err {
+				t.Fatalf("expected error: %v, got: %v", tc.err, err)
+			}
+			if limit != tc.limit {
+				t.Fatalf("expected limit: %v, got: %v", tc.limit, limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package node
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/errors"
+	"github.com/SkycoinProject/cxo-2/pkg/logging"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+)
+
+// seedChunkSize - number of bytes written at once while bandwidth is limited
+const seedChunkSize = 32 * 1024
+
+// seeding - limits of bandwidth used for serving data to other nodes
+type seeding struct {
+	total *limiter
+	mux   sync.Mutex
+	feeds map[string]*limiter
+}
+
+func newSeeding() *seeding {
+	return &seeding{feeds: make(map[string]*limiter)}
+}
+
+// limiter - spreads writes so that no more than bytesPerSecond is written on average, nil limiter doesn't limit
+type limiter struct {
+	bytesPerSecond uint64
+	mux            sync.Mutex
+	next           time.Time
+}
+
+func newLimiter(bytesPerSecond uint64) *limiter {
+	if bytesPerSecond == 0 {
+		return nil
+	}
+	return &limiter{bytesPerSecond: bytesPerSecond}
+}
+
+// wait - block until n bytes can be written
+func (l *limiter) wait(n int) {
+	if l == nil {
+		return
+	}
+	l.mux.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(uint64(n) * uint64(time.Second) / l.bytesPerSecond))
+	l.mux.Unlock()
+	time.Sleep(delay)
+}
+
+// servesFeed - whether data of the publisher's feed is served to other nodes. Without seeding configuration,
+// nodes exchanging data serve every feed they hold.
+func (s *Service) servesFeed(publisher string) bool {
+	if s.config.Seeding.Enabled() {
+		return s.config.Seeding.Seeds(publisher)
+	}
+	return s.config.P2P.Enabled
+}
+
+// headerFeed - publisher of the feed stored object header belongs to
+func (s *Service) headerFeed(hash string) (string, error) {
+	rootHashKey, err := s.db.GetObjectHeaderRootHashKey(hash)
+	if err != nil {
+		return "", err
+	}
+	return strings.SplitN(rootHashKey, "_", 2)[0], nil
+}
+
+// objectHeadersHandler - serve stored object headers of seeded feeds, request and response are the same as
+// tracker's. Headers of other feeds are reported missing.
+func (s *Service) objectHeadersHandler(w http.ResponseWriter, r *http.Request) {
+	hashes := r.URL.Query()["hash"]
+	if r.Method != http.MethodGet || len(hashes) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	var feed string
+	resp := model.GetObjectHeadersResponse{ObjectHeaders: make([]model.ObjectHeader, 0, len(hashes))}
+	for _, hash := range hashes {
+		publisher, err := s.headerFeed(hash)
+		if err == nil && !s.servesFeed(publisher) {
+			err = errors.ErrCannotFindObjectHeader
+		}
+		var header model.ObjectHeader
+		if err == nil {
+			header, err = s.db.GetObjectHeader(hash)
+		}
+		if err == errors.ErrCannotFindObjectHeader {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		feed = publisher
+		resp.ObjectHeaders = append(resp.ObjectHeaders, header)
+	}
+	s.writeSeeded(w, feed, resp)
+}
+
+// objectHandler - serve stored object of seeded feed, request and response are the same as tracker's. Objects of
+// other feeds are reported missing.
+func (s *Service) objectHandler(w http.ResponseWriter, r *http.Request) {
+	hash := r.URL.Query().Get("hash")
+	if r.Method != http.MethodGet || hash == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var publisher string
+	headerHash, err := s.db.GetObjectHeaderHashOfObject(hash)
+	if err == nil {
+		publisher, err = s.headerFeed(headerHash)
+	}
+	if err == nil && !s.servesFeed(publisher) {
+		err = errors.ErrCannotFindObject
+	}
+	var object model.Object
+	if err == nil {
+		object, err = s.db.GetObject(hash)
+	}
+	if err == errors.ErrCannotFindObject || err == errors.ErrCannotFindObjectHeader {
+		http.Error(w, errors.ErrCannotFindObject.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeSeeded(w, publisher, object)
+}
+
+// writeSeeded - write JSON response with data of the feed within bandwidth limits
+func (s *Service) writeSeeded(w http.ResponseWriter, feed string, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	feedLimiter := s.seeding.feedLimiter(feed, s.config.Seeding.FeedBytesPerSecond)
+	w.Header().Set("Content-Type", "application/json")
+	for len(body) > 0 {
+		n := seedChunkSize
+		if n > len(body) {
+			n = len(body)
+		}
+		s.seeding.total.wait(n)
+		feedLimiter.wait(n)
+		if _, err := w.Write(body[:n]); err != nil {
+			s.logger.WithField(logging.PublisherField, feed).WithError(err).Debug("Serving data failed")
+			return
+		}
+		body = body[n:]
+	}
+}
+
+func (s *seeding) feedLimiter(feed string, bytesPerSecond uint64) *limiter {
+	if bytesPerSecond == 0 {
+		return nil
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	l, ok := s.feeds[feed]
+	if !ok {
+		l = newLimiter(bytesPerSecond)
+		s.feeds[feed] = l
+	}
+	return l
+}
@@ -0,0 +1,197 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+)
+
+func TestSeedingHandlers(t *testing.T) {
+	seededReq := newTestFeed(t, testDirectory("seeded", testEntry("first", "seeded content")))
+	otherReq := newTestFeed(t, testDirectory("other", testEntry("second", "other content")))
+	seeded, other := seededReq.RootHash.Publisher, otherReq.RootHash.Publisher
+
+	tests := []struct {
+		name string
+		cfg  config.Config
+		// served - publishers whose data is expected to be served
+		served map[string]bool
+	}{
+		{name: "seeded feed", cfg: config.Config{Seeding: config.SeedingConfig{Feeds: []string{seeded}}},
+			served: map[string]bool{seeded: true}},
+		{name: "every feed seeded", cfg: config.Config{Seeding: config.SeedingConfig{Feeds: []string{config.AllFeeds}}},
+			served: map[string]bool{seeded: true, other: true}},
+		// seeding configuration limits feeds served by nodes exchanging data
+		{name: "seeded feed with P2P", served: map[string]bool{seeded: true},
+			cfg: config.Config{P2P: config.P2PConfig{Enabled: true}, Seeding: config.SeedingConfig{Feeds: []string{seeded}}}},
+		{name: "P2P without seeding configuration", cfg: config.Config{P2P: config.P2PConfig{Enabled: true}},
+			served: map[string]bool{seeded: true, other: true}},
+		{name: "neither seeding nor P2P", served: map[string]bool{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestService(t, tc.cfg)
+			feeds := map[string]model.PublishDataRequest{seeded: seededReq, other: otherReq}
+			contents := map[string]string{seeded: "seeded content", other: "other content"}
+			headers := make(map[string]string)
+			for publisher, req := range feeds {
+				feed := storeTestFeed(t, s, req)
+				hash, header := headerOf(t, feed, contents[publisher])
+				headers[publisher] = hash
+
+				expected := http.StatusNotFound
+				if tc.served[publisher] {
+					expected = http.StatusOK
+				}
+
+				rec := httptest.NewRecorder()
+				s.objectHeadersHandler(rec, httptest.NewRequest(http.MethodGet, objectHeaderRoute+"?hash="+hash, nil))
+				if rec.Code != expected {
+					t.Fatalf("expected status: %v for object header of %v, got: %v", expected, publisher, rec.Code)
+				}
+				if expected == http.StatusOK {
+					var resp model.GetObjectHeadersResponse
+					if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+						t.Fatal(err)
+					}
+					if len(resp.ObjectHeaders) != 1 || resp.ObjectHeaders[0].ObjectHash != header.ObjectHash {
+						t.Fatalf("expected object header: %+v, got: %+v", header, resp.ObjectHeaders)
+					}
+				}
+
+				rec = httptest.NewRecorder()
+				s.objectHandler(rec, httptest.NewRequest(http.MethodGet, objectRoute+"?hash="+header.ObjectHash, nil))
+				if rec.Code != expected {
+					t.Fatalf("expected status: %v for object of %v, got: %v", expected, publisher, rec.Code)
+				}
+				if expected == http.StatusOK {
+					var object model.Object
+					if err := json.Unmarshal(rec.Body.Bytes(), &object); err != nil {
+						t.Fatal(err)
+					}
+					if string(object.Data) != contents[publisher] {
+						t.Fatalf("expected object with data: %q, got: %q", contents[publisher], object.Data)
+					}
+				}
+			}
+
+			// headers requested together are served only if all of them are
+			expected := http.StatusNotFound
+			if tc.served[seeded] && tc.served[other] {
+				expected = http.StatusOK
+			}
+			rec := httptest.NewRecorder()
+			url := fmt.Sprint(objectHeaderRoute, "?hash=", headers[seeded], "&hash=", headers[other])
+			s.objectHeadersHandler(rec, httptest.NewRequest(http.MethodGet, url, nil))
+			if rec.Code != expected {
+				t.Fatalf("expected status: %v for object headers of both feeds, got: %v", expected, rec.Code)
+			}
+
+			for _, route := range []string{objectHeaderRoute, objectRoute} {
+				rec := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodGet, route+"?hash=unknown", nil)
+				if route == objectRoute {
+					s.objectHandler(rec, req)
+				} else {
+					s.objectHeadersHandler(rec, req)
+				}
+				if rec.Code != http.StatusNotFound {
+					t.Fatalf("expected status: %v for unknown hash on route: %v, got: %v", http.StatusNotFound, route,
+						rec.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	tests := []struct {
+		name           string
+		bytesPerSecond uint64
+		writes         []int
+		// min - shortest time writes are expected to take
+		min time.Duration
+	}{
+		{name: "unlimited", writes: []int{1000, 1000, 1000}},
+		// the first write isn't delayed, each following one waits for bandwidth used by the previous ones
+		{name: "limited", bytesPerSecond: 10000, writes: []int{1000, 1000, 1000}, min: 200 * time.Millisecond},
+		{name: "single write", bytesPerSecond: 10, writes: []int{1000}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newLimiter(tc.bytesPerSecond)
+			if (l == nil) != (tc.bytesPerSecond == 0) {
+				t.Fatalf("expected limiter only for bandwidth limit, got: %+v", l)
+			}
+			start := time.Now()
+			for _, n := range tc.writes {
+				l.wait(n)
+			}
+			elapsed := time.Since(start)
+			if elapsed < tc.min {
+				t.Fatalf("expected writes to take at least %v, took: %v", tc.min, elapsed)
+			}
+			// generous bound, so that slow machine doesn't fail the test
+			if elapsed > tc.min+time.Second {
+				t.Fatalf("expected writes to take about %v, took: %v", tc.min, elapsed)
+			}
+		})
+	}
+}
+
+func TestWriteSeeded(t *testing.T) {
+	object := model.Object{Length: 3 * seedChunkSize, Data: make([]byte, 3*seedChunkSize)}
+	body, err := json.Marshal(object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// last chunk is written once bandwidth used by all the previous ones is available, which takes 250ms
+	last := len(body) % seedChunkSize
+	if last == 0 {
+		last = seedChunkSize
+	}
+	bytesPerSecond := uint64(len(body)-last) * 4
+
+	tests := []struct {
+		name string
+		cfg  config.SeedingConfig
+		min  time.Duration
+	}{
+		{name: "unlimited"},
+		{name: "total bandwidth", cfg: config.SeedingConfig{BytesPerSecond: bytesPerSecond}, min: 240 * time.Millisecond},
+		{name: "feed bandwidth", cfg: config.SeedingConfig{FeedBytesPerSecond: bytesPerSecond},
+			min: 240 * time.Millisecond},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.cfg.Feeds = []string{config.AllFeeds}
+			s := newTestService(t, config.Config{Seeding: tc.cfg})
+
+			rec := httptest.NewRecorder()
+			start := time.Now()
+			s.writeSeeded(rec, "publisher", object)
+			elapsed := time.Since(start)
+			if rec.Body.String() != string(body) {
+				t.Fatal("expected object to be written whole")
+			}
+			if elapsed < tc.min || elapsed > tc.min+time.Second {
+				t.Fatalf("expected writing to take about %v, took: %v", tc.min, elapsed)
+			}
+		})
+	}
+
+	// every feed has its own bandwidth
+	s := newTestService(t, config.Config{Seeding: config.SeedingConfig{FeedBytesPerSecond: 1}})
+	if s.seeding.feedLimiter("first", 1) == s.seeding.feedLimiter("second", 1) {
+		t.Fatal("expected feeds to have separate limiters")
+	}
+	if s.seeding.feedLimiter("first", 1) != s.seeding.feedLimiter("first", 1) {
+		t.Fatal("expected feed to keep its limiter")
+	}
+}
@@ -2,62 +2,129 @@ package node
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/SkycoinProject/cxo-2/pkg/errors"
 
 	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/logging"
 	"github.com/SkycoinProject/cxo-2/pkg/model"
 	"github.com/SkycoinProject/cxo-2/pkg/node/data"
-	dmsghttp "github.com/SkycoinProject/dmsg-http"
-	dmsgcipher "github.com/SkycoinProject/dmsg/cipher"
+	"github.com/SkycoinProject/cxo-2/pkg/parcel"
+	"github.com/SkycoinProject/cxo-2/pkg/signature"
+	"github.com/SkycoinProject/cxo-2/pkg/tracker"
+	"github.com/SkycoinProject/cxo-2/pkg/transport"
 	log "github.com/sirupsen/logrus"
 )
 
 // Service - node service model
 type Service struct {
-	config config.Config
-	db     data.Data
+	config   config.Config
+	db       data.Data
+	client   *http.Client
+	queue    *feedQueue
+	feeds    *feedLocks
+	trackers *tracker.Pool
+	peers    *peerSet
+	seeding  *seeding
+	requests *inFlight
+	server   transport.Server
+	metrics  *nodeMetrics
+	logger   log.FieldLogger
+	// trackerHosts - addresses notifications from trackers reached over HTTP are accepted from
+	trackerHosts *hostResolver
+	// interrupted - done when downloads have to stop before they finish, so node can shut down
+	interrupted        context.Context
+	interruptDownloads context.CancelFunc
 }
 
-// NewService - initialize node service
-func NewService(cfg config.Config) *Service {
-	return &Service{
-		config: cfg,
-		db:     data.DefaultData(),
-	}
+// NewService - initialize node service which logs to the logger
+func NewService(cfg config.Config, db data.Data, logger log.FieldLogger) *Service {
+	s := &Service{
+		config:   cfg,
+		db:       db,
+		logger:   logger,
+		client:   transport.NewClient(cfg.Discovery, cfg.PubKey, cfg.SecKey),
+		trackers: tracker.NewPool(cfg.Trackers, logger),
+		peers:    newPeerSet(),
+		seeding:  newSeeding(),
+		requests: &inFlight{},
+		feeds:    newFeedLocks(),
+	}
+	s.interrupted, s.interruptDownloads = context.WithCancel(context.Background())
+	s.metrics = newNodeMetrics(s)
+	s.trackerHosts = newHostResolver()
+	s.seeding.total = newLimiter(cfg.Seeding.BytesPerSecond)
+	s.queue = newFeedQueue(func(rootHash model.RootHash) bool {
+		unlock := s.feeds.lock(rootHash.Publisher)
+		defer unlock()
+		s.requestData(rootHash, false)
+		_, err := s.db.GetRootHash(rootHash.Key())
+		return err == nil
+	}, s.discardRootHash)
+	return s
 }
 
 var notifyRoute = "/notify"
 
-// Run - start's node service
-func (s *Service) Run() {
-	webServer := InitServerAndController(s.db)
-	go func() {
-		webServer.Run()
-	}()
+// maxTimestampDrift - how far in the future root hash timestamp can be, to tolerate clock differences
+const maxTimestampDrift = 10 * time.Minute
 
-	httpS := dmsghttp.Server{
-		PubKey:    s.config.PubKey,
-		SecKey:    s.config.SecKey,
-		Port:      s.config.Port,
-		Discovery: s.config.Discovery,
-	}
+// trackerHealthCheckInterval - how often trackers are probed, so that failover doesn't wait for failed requests
+const trackerHealthCheckInterval = time.Minute
 
-	log.Infof("Starting cxo node with public key: %s and port: %v", s.config.PubKey.Hex(), s.config.Port)
+// Run - start node service and run it until context is done or one of its servers fails. Node is shut down
+// gracefully before returning, so data store can be closed afterwards.
+func (s *Service) Run(ctx context.Context) error {
+	httpS := transport.NewServer(s.config)
+	s.server = httpS
+	webServer := InitServerAndController(s)
 
 	// prepare server route handling
 	mux := http.NewServeMux()
 	mux.HandleFunc(notifyRoute, s.notifyHandler)
+	if s.config.P2P.Enabled || s.config.Seeding.Enabled() {
+		mux.HandleFunc(objectHeaderRoute, s.objectHeadersHandler)
+		mux.HandleFunc(objectRoute, s.objectHandler)
+	}
+
+	s.logger.WithFields(log.Fields{
+		"pubKey":    s.config.PubKey.Hex(),
+		"transport": s.config.Server.Transport,
+		"address":   transport.Address(s.config),
+	}).Info("Starting cxo node")
+
+	sErr := make(chan error, 2)
+	go func() {
+		if err := webServer.Run(); err != nil {
+			sErr <- fmt.Errorf("local API failed due to error: %v", err)
+		}
+	}()
+	go func() {
+		if err := httpS.Serve(s.requests.track(mux)); err != nil {
+			sErr <- fmt.Errorf("node server failed due to error: %v", err)
+		}
+	}()
 
-	// run the server
-	sErr := make(chan error, 1)
-	sErr <- httpS.Serve(mux)
-	close(sErr)
+	healthCtx, stopHealthChecks := context.WithCancel(ctx)
+	defer stopHealthChecks()
+	go s.resumeDownloads()
+	go s.trackers.RunHealthChecks(healthCtx, s.client, trackerHealthCheckInterval)
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-sErr:
+		s.logger.Error(err)
+	}
+	s.shutdown(webServer, httpS)
+	return err
 }
 
 func (s *Service) notifyHandler(w http.ResponseWriter, r *http.Request) {
@@ -65,49 +132,190 @@ func (s *Service) notifyHandler(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	trackerID, err := s.authenticateTracker(r.RemoteAddr)
+	if err != nil {
+		s.logger.WithField("remote", r.RemoteAddr).WithError(err).Warn("Refusing notification")
+		s.metrics.notificationsReceived.Inc(notificationRefused)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	var rootHash model.RootHash
-	err := json.NewDecoder(r.Body).Decode(&rootHash)
+	err = json.NewDecoder(r.Body).Decode(&rootHash)
 	if err != nil {
-		log.Error("Error while receiving new root hash: ", err)
+		s.logger.WithField("remote", r.RemoteAddr).WithError(err).Error("Decoding received root hash failed")
+		s.metrics.notificationsReceived.Inc(notificationInvalid)
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
 
-	fmt.Println("Received new root hash from cxo tracker service: ", rootHash.Key())
+	logger := s.rootHashLogger(rootHash).WithField("remote", r.RemoteAddr)
+	logger.Info("Received new root hash")
 
-	go func() {
-		time.Sleep(3 * time.Second)
-		s.requestData(rootHash, false)
-	}()
+	if err := s.checkPublisherTrusted(rootHash.Publisher); err != nil {
+		logger.WithError(err).Warn("Rejecting root hash")
+		s.metrics.notificationsReceived.Inc(notificationResult(err))
+		http.Error(w, err.Error(), validationErrorStatus(err))
+		return
+	}
+
+	if err := s.validateRootHash(rootHash); err != nil {
+		logger.WithError(err).Warn("Rejecting root hash")
+		s.metrics.notificationsReceived.Inc(notificationResult(err))
+		http.Error(w, err.Error(), validationErrorStatus(err))
+		return
+	}
+
+	if err := s.checkSignatureVersion(rootHash); err != nil {
+		logger.WithError(err).Warn("Rejecting root hash")
+		s.metrics.notificationsReceived.Inc(notificationResult(err))
+		http.Error(w, err.Error(), validationErrorStatus(err))
+		return
+	}
+
+	// forged root hash is refused before it's persisted and queued, where it would supersede valid ones
+	if rootHash.SignatureVersion == signature.RootHashVersion {
+		if err := signature.VerifyRootHash(rootHash); err != nil {
+			logger.WithError(err).Warn("Rejecting root hash with invalid signature")
+			s.metrics.notificationsReceived.Inc(notificationInvalid)
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	if _, err := s.db.GetRootHash(rootHash.Key()); err == nil {
+		logger.Info("Root hash is already stored")
+		s.metrics.notificationsReceived.Inc(notificationDuplicate)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if download, err := s.db.GetDownload(rootHash.Key()); err == nil && download.Status != model.DownloadFailed {
+		logger.Infof("Download of root hash is already %v", download.Status)
+		s.metrics.notificationsReceived.Inc(notificationDuplicate)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// tracker that sent the root hash is expected to have its data
+	s.trackers.Prefer(rootHash.Publisher, trackerID)
+
+	// download is persisted before responding so it can be resumed if node stops before retrieving the data
+	if err := s.saveDownloadStatus(rootHash, model.DownloadPending, nil); err != nil {
+		logger.WithError(err).Error("Saving download failed")
+		s.metrics.notificationsReceived.Inc(notificationFailed)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.queue.push(rootHash)
+	s.metrics.notificationsReceived.Inc(notificationAccepted)
 
 	w.WriteHeader(http.StatusOK)
 }
 
 func (s *Service) requestData(rootHash model.RootHash, isRetry bool) {
+	logger := s.rootHashLogger(rootHash)
 	_, err := s.db.GetRootHash(rootHash.Key())
 	if err == nil {
-		fmt.Printf("received root hash with key: %v already exist \n", rootHash.Key())
+		logger.Info("Root hash is already stored")
 		return
 	}
 	if err != errors.ErrCannotFindRootHash {
-		fmt.Print(err.Error())
+		logger.WithError(err).Error("Fetching root hash failed")
 		return
 	}
 
-	if err := s.db.SaveRootHash(rootHash); err != nil {
-		fmt.Printf("saving root hash with key: %v failed due to error: %v", rootHash.Key(), err)
+	// feed could be updated while root hash was waiting in the queue
+	if err := s.validateRootHash(rootHash); err != nil {
+		logger.WithError(err).Warn("Rejecting root hash")
+		s.failDownload(rootHash, err)
 		return
 	}
 
-	client := dmsghttp.DMSGClient(s.config.Discovery, s.config.PubKey, s.config.SecKey)
+	// publisher could be blocked or unsubscribed while root hash was waiting in the queue
+	if err := s.checkPublisherTrusted(rootHash.Publisher); err != nil {
+		logger.WithError(err).Warn("Rejecting root hash")
+		s.failDownload(rootHash, err)
+		return
+	}
 
-	if err := s.retrieveHeaders(client, rootHash, rootHash.ObjectHeaderHash); err != nil {
-		fmt.Printf("retrieveing headers failed due to error: %v", err)
+	if err := s.checkFeedPaused(rootHash); err != nil {
+		logger.WithError(err).Info("Refusing root hash")
+		s.failDownload(rootHash, err)
 		return
 	}
 
-	parcel, isValid := s.checkSignature(rootHash)
-	s.db.RemoveUnreferencedObjects(rootHash.Key(), isValid)
+	if err := s.saveDownloadStatus(rootHash, model.DownloadFetching, nil); err != nil {
+		logger.WithError(err).Error("Saving download failed")
+		return
+	}
+
+	r := &retrieval{
+		client:   s.client,
+		rootHash: rootHash,
+	}
+
+	// root header declares size of the whole feed, so it's known before anything else is retrieved
+	rootHeaders, err := s.fetchCheckedHeaders(r.client, rootHash.Publisher, rootHash.ObjectHeaderHash)
+	if err != nil {
+		logger.WithError(err).Error("Retrieving object headers failed")
+		s.failDownload(rootHash, err)
+		return
+	}
+	size := parcel.TotalSize(rootHeaders[0])
+	logger.Infof("Retrieving %v bytes of data", size)
+	if err := s.saveDownloadSize(rootHash, size); err != nil {
+		logger.WithError(err).Error("Saving download failed")
+		return
+	}
+
+	if err := s.admitRootHash(rootHash, size); err != nil {
+		logger.WithError(err).Warn("Refusing root hash")
+		s.failDownload(rootHash, err)
+		return
+	}
+	// feeds of older publishers may not declare their size, but they still can't exceed the quota
+	if r.limit, err = s.downloadLimit(rootHash, size); err != nil {
+		logger.WithError(err).Warn("Refusing root hash")
+		s.failDownload(rootHash, err)
+		return
+	}
+	r.limitExceeded = errors.ErrQuotaExceeded
+	if r.limit == size {
+		r.limitExceeded = errors.ErrDeclaredSizeExceeded
+	}
+
+	if err := s.storeHeaders(r, []string{rootHash.ObjectHeaderHash}, rootHeaders); err != nil {
+		if s.interrupted.Err() != nil {
+			logger.Info("Download is interrupted, it's resumed on next start")
+			return
+		}
+		logger.WithError(err).Error("Retrieving object headers failed")
+		s.failDownload(rootHash, err)
+		return
+	}
+
+	if err := s.saveDownloadStatus(rootHash, model.DownloadVerifying, nil); err != nil {
+		logger.WithError(err).Error("Saving download failed")
+		return
+	}
+
+	err = s.checkSignature(rootHash)
+	isValid := err == nil
+	if !isValid {
+		logger.WithError(err).Warn("Signature verification failed")
+		s.metrics.signatureFailures.Inc()
+	}
+	if isValid {
+		// root hash is stored only once all of its data is retrieved and verified
+		if err := s.db.SaveRootHash(rootHash); err != nil {
+			logger.WithError(err).Error("Saving root hash failed")
+			s.failDownload(rootHash, err)
+			return
+		}
+	}
+	s.metrics.gcReclaimedBytes.Add(float64(s.db.RemoveUnreferencedObjects(rootHash.Key(), isValid)))
 
 	if !isValid && !isRetry {
 		s.requestData(rootHash, true)
@@ -115,115 +323,342 @@ func (s *Service) requestData(rootHash model.RootHash, isRetry bool) {
 	}
 
 	if !isValid {
-		fmt.Printf("Signature is not valid. All data from feed: %s is removed...", rootHash.Publisher)
+		logger.Warn("Signature is not valid after retry, all data of the feed is removed")
+		s.failDownload(rootHash, fmt.Errorf("signature is not valid"))
+		return
+	}
+
+	if err := s.saveDownloadStatus(rootHash, model.DownloadComplete, nil); err != nil {
+		logger.WithError(err).Error("Saving download failed")
+	}
+	s.notifyRegisteredApps(rootHash)
+	logger.Info("Retrieving new data finished successfully")
+}
+
+// validateRootHash - protect feed from replayed or forked root hashes, which would roll it back to older data,
+// and from root hashes claiming to be published in the future
+func (s *Service) validateRootHash(rootHash model.RootHash) error {
+	if rootHash.Timestamp.After(time.Now().Add(maxTimestampDrift)) {
+		return errors.ErrTimestampInFuture
+	}
+
+	latest, err := s.db.GetLatestRootHash(rootHash.Publisher)
+	if err != nil {
+		if err == errors.ErrCannotFindRootHash {
+			return nil
+		}
+		return fmt.Errorf("fetching latest root hash of publisher: %v failed due to error: %v", rootHash.Publisher, err)
+	}
+
+	if rootHash.Sequence < latest.Sequence {
+		return errors.ErrStaleSequence
+	}
+	if rootHash.Sequence == latest.Sequence && (rootHash.ObjectHeaderHash != latest.ObjectHeaderHash ||
+		rootHash.Signature != latest.Signature || !rootHash.Timestamp.Equal(latest.Timestamp)) {
+		return errors.ErrForkedSequence
+	}
+	return nil
+}
+
+func validationErrorStatus(err error) int {
+	switch err {
+	case errors.ErrStaleSequence, errors.ErrForkedSequence:
+		return http.StatusConflict
+	case errors.ErrTimestampInFuture, errors.ErrLegacySignature:
+		return http.StatusUnprocessableEntity
+	case errors.ErrNotSubscribed, errors.ErrPublisherBlocked:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// rootHashLogger - logger with fields identifying the root hash, so all entries about its download can be found
+func (s *Service) rootHashLogger(rootHash model.RootHash) log.FieldLogger {
+	return s.logger.WithFields(log.Fields{
+		logging.PublisherField: rootHash.Publisher,
+		logging.SequenceField:  rootHash.Sequence,
+		logging.HashField:      rootHash.ObjectHeaderHash,
+	})
+}
+
+// notificationResult - result of notification refused due to the error
+func notificationResult(err error) string {
+	switch validationErrorStatus(err) {
+	case http.StatusForbidden:
+		return notificationRefused
+	case http.StatusInternalServerError:
+		return notificationFailed
+	default:
+		return notificationInvalid
+	}
+}
+
+// resumeDownloads - continue downloads that were interrupted by node shutdown
+func (s *Service) resumeDownloads() {
+	downloads, err := s.db.GetUnfinishedDownloads()
+	if err != nil {
+		s.logger.WithError(err).Error("Fetching unfinished downloads failed")
 		return
 	}
-	s.notifyRegisteredApps(rootHash, parcel)
-	fmt.Println("Retrieving new data finished successfully")
+
+	// queue drops lower sequences so downloads are pushed in order they were published
+	sort.Slice(downloads, func(i, j int) bool {
+		return downloads[i].RootHash.Sequence < downloads[j].RootHash.Sequence
+	})
+	for _, download := range downloads {
+		s.rootHashLogger(download.RootHash).Infof("Resuming %v download", download.Status)
+		s.queue.push(download.RootHash)
+	}
 }
 
-func (s *Service) notifyRegisteredApps(rootHash model.RootHash, parcel model.Parcel) {
+// discardRootHash - mark download of root hash that queue dropped without processing as failed
+func (s *Service) discardRootHash(rootHash model.RootHash, reason string) {
+	s.rootHashLogger(rootHash).Infof("Root hash is dropped: %v", reason)
+	s.failDownload(rootHash, fmt.Errorf("root hash dropped: %v", reason))
+}
+
+func (s *Service) saveDownloadStatus(rootHash model.RootHash, status model.DownloadStatus, cause error) error {
+	download := model.Download{
+		RootHash: rootHash,
+	}
+	// size is known only once root header is retrieved, so it's kept from previous status
+	if existing, err := s.db.GetDownload(rootHash.Key()); err == nil {
+		download.Size = existing.Size
+	}
+	download.Status = status
+	download.UpdatedAt = time.Now()
+	if cause != nil {
+		download.Error = cause.Error()
+	}
+	return s.db.SaveDownload(download)
+}
+
+func (s *Service) saveDownloadSize(rootHash model.RootHash, size uint64) error {
+	download, err := s.db.GetDownload(rootHash.Key())
+	if err != nil {
+		return err
+	}
+	download.Size = size
+	download.UpdatedAt = time.Now()
+	return s.db.SaveDownload(download)
+}
+
+func (s *Service) failDownload(rootHash model.RootHash, cause error) {
+	if err := s.saveDownloadStatus(rootHash, model.DownloadFailed, cause); err != nil {
+		s.rootHashLogger(rootHash).WithError(err).Error("Marking download as failed returned error")
+	}
+}
+
+func (s *Service) notifyRegisteredApps(rootHash model.RootHash) {
 	addresses, err := s.db.GetAllRegisteredApps()
 	if err != nil {
-		fmt.Println("Error while fetching registered apps: ", err)
+		s.logger.WithError(err).Error("Fetching registered apps failed")
+		return
+	}
+	if len(addresses) == 0 {
+		return
+	}
+
+	feedParcel, err := parcel.Recreate(s.db, rootHash.ObjectHeaderHash)
+	if err != nil {
+		s.rootHashLogger(rootHash).WithError(err).Error("Recreating parcel for registered apps failed")
 		return
 	}
 	notifyRequest := model.NotifyAppRequest{
 		RootHash: rootHash,
-		Parcel:   parcel,
+		Parcel:   feedParcel,
+	}
+	b, err := json.Marshal(notifyRequest)
+	if err != nil {
+		s.rootHashLogger(rootHash).WithError(err).Error("Marshalling notification for registered apps failed")
+		return
 	}
-	b := new(bytes.Buffer)
-	json.NewEncoder(b).Encode(notifyRequest)
 
 	client := http.DefaultClient
 	for _, address := range addresses {
-		_, err := client.Post(fmt.Sprint("http://", address), "application/json", b)
+		logger := s.rootHashLogger(rootHash).WithField("app", address)
+		resp, err := client.Post(fmt.Sprint("http://", address), "application/json", bytes.NewReader(b))
 		if err != nil {
-			fmt.Printf("Notify app with address: %v failed due to error %v: ", address, err)
+			logger.WithError(err).Warn("Notifying app failed")
+			s.metrics.appNotifications.Inc(appNotifyFailed)
+			continue
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= http.StatusMultipleChoices {
+			logger.Warnf("Notifying app returned status: %v", resp.Status)
+			s.metrics.appNotifications.Inc(appNotifyFailed)
 			continue
 		}
-		fmt.Printf("App with address: %v notified succesfully.", address)
+		s.metrics.appNotifications.Inc(appNotified)
+		logger.Info("App notified successfully")
+	}
+}
+
+// retrieval - state of retrieving data of single root hash
+type retrieval struct {
+	client   *http.Client
+	rootHash model.RootHash
+	// limit - number of object bytes that can be retrieved, 0 if not limited
+	limit uint64
+	// limitExceeded - error download fails with when limit is exceeded
+	limitExceeded error
+	retrieved     uint64
+}
+
+func (s *Service) retrieveHeaders(r *retrieval, headerHashes ...string) error {
+	if s.interrupted.Err() != nil {
+		return errors.ErrDownloadInterrupted
+	}
+	headers, err := s.fetchCheckedHeaders(r.client, r.rootHash.Publisher, headerHashes...)
+	if err != nil {
+		return err
 	}
+	return s.storeHeaders(r, headerHashes, headers)
 }
 
-func (s *Service) retrieveHeaders(client *http.Client, rootHash model.RootHash, headerHashes ...string) error {
-	headers, err := s.fetchObjectHeaders(client, headerHashes...)
+// fetchCheckedHeaders - fetch object headers of the feed and make sure they are the requested ones. Peer or tracker
+// that fails or sends invalid headers is replaced by the next one.
+func (s *Service) fetchCheckedHeaders(client *http.Client, feed string, headerHashes ...string) ([]model.ObjectHeader, error) {
+	var headers []model.ObjectHeader
+	start := time.Now()
+	err := s.fetch(client, feed, func(address string) error {
+		var err error
+		headers, err = s.fetchObjectHeaders(client, address, headerHashes...)
+		if err != nil {
+			return fmt.Errorf("fetching object headers with hashes: %v from service failed due to error: %w", headerHashes, err)
+		}
+		if len(headers) != len(headerHashes) {
+			return fmt.Errorf("requested %v object headers but received %v", len(headerHashes), len(headers))
+		}
+		for i, header := range headers {
+			if err := parcel.CheckHash(header, headerHashes[i]); err != nil {
+				return fmt.Errorf("received invalid object header: %v", err)
+			}
+			if err := parcel.CheckHeaderSizes(header); err != nil {
+				return fmt.Errorf("received object header: %v with invalid size fields: %v", headerHashes[i], err)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("fetching object headers with hashes: %v from service failed due to error: %v", headerHashes, err)
+		return nil, err
 	}
+	s.metrics.observeFetch(fetchObjectHeaders, start)
+	s.metrics.headersFetched.Add(float64(len(headers)))
+	return headers, nil
+}
+
+// storeHeaders - save fetched object headers and retrieve everything they reference that is not stored yet
+func (s *Service) storeHeaders(r *retrieval, headerHashes []string, headers []model.ObjectHeader) error {
 	var missingHeaderHashes []string
 	for i, header := range headers {
-		for _, ref := range header.ExternalReferences {
-			existingHeader, err := s.db.GetObjectHeader(ref)
-			if err != nil {
-				if err == errors.ErrCannotFindObjectHeader {
-					missingHeaderHashes = append(missingHeaderHashes, ref)
-					continue
-				}
-				return fmt.Errorf("fetching object header with hash: %v from db failed due to error: %v", ref, err)
-			} else {
-				// update existing object header to newest sequence
-				if err := s.UpdateObjectHeaderRootHashKey(ref, rootHash.Key(), existingHeader); err != nil {
-					return err
-				}
-			}
-		}
 		// save missing object header
-		if err := s.db.SaveObjectHeader(headerHashes[i], rootHash, header); err != nil {
+		if err := s.db.SaveObjectHeader(headerHashes[i], r.rootHash, header); err != nil {
 			return fmt.Errorf("saving object header with hash: %v failed due to error: %v", headerHashes[i], err)
 		}
 
-		if len(header.ObjectHash) > 0 {
-			// fetch and save missing object
-			if err := s.fetchAndSaveObject(header.ObjectHash, headerHashes[i], client); err != nil {
-				return err
-			}
+		missing, err := s.completeHeader(r, headerHashes[i], header)
+		if err != nil {
+			return err
 		}
+		missingHeaderHashes = append(missingHeaderHashes, missing...)
 	}
 
 	if len(missingHeaderHashes) > 0 {
-		if err := s.retrieveHeaders(client, rootHash, missingHeaderHashes...); err != nil {
+		if err := s.retrieveHeaders(r, missingHeaderHashes...); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (s *Service) fetchAndSaveObject(hash, objectHeaderHash string, client *http.Client) error {
-	object, err := s.fetchObject(client, hash)
-	if err != nil {
-		return fmt.Errorf("fetch object with hash: %v failed due to error: %v", hash, err)
-	}
-
-	if err := s.db.SaveObject(hash, objectHeaderHash, object); err != nil {
-		return fmt.Errorf("saving object with hash: %v failed due to error: %v", hash, err)
+// completeHeader - fetch object of the stored header if it's missing and update already stored references to
+// the newest sequence. Hashes of referenced headers that are not stored yet are returned so they can be fetched.
+// Since every stored header is checked this way, download interrupted at any point can be resumed.
+func (s *Service) completeHeader(r *retrieval, hash string, header model.ObjectHeader) ([]string, error) {
+	if len(header.ObjectHash) > 0 {
+		exists, err := s.db.HasObject(header.ObjectHash)
+		if err != nil {
+			return nil, fmt.Errorf("checking object with hash: %v in db failed due to error: %v", header.ObjectHash, err)
+		}
+		if !exists {
+			// fetch and save missing object
+			if err := s.fetchAndSaveObject(r, header, hash); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	return nil
-}
+	var missingHeaderHashes []string
+	for _, ref := range header.ExternalReferences {
+		existingHeader, err := s.db.GetObjectHeader(ref)
+		if err != nil {
+			if err == errors.ErrCannotFindObjectHeader {
+				missingHeaderHashes = append(missingHeaderHashes, ref)
+				continue
+			}
+			return nil, fmt.Errorf("fetching object header with hash: %v from db failed due to error: %v", ref, err)
+		}
 
-// Update object header and all references to the newest sequence
-func (s *Service) UpdateObjectHeaderRootHashKey(hash, rootHashKey string, header model.ObjectHeader) error {
-	if err := s.db.UpdateObjectHeaderRootHashKey(hash, rootHashKey); err != nil {
-		return fmt.Errorf("updating object header with hash: %v failed due to error: %v", hash, err)
+		// update existing object header to newest sequence
+		if err := s.db.UpdateObjectHeaderRootHashKey(ref, r.rootHash.Key()); err != nil {
+			return nil, fmt.Errorf("updating object header with hash: %v failed due to error: %v", ref, err)
+		}
+		missing, err := s.completeHeader(r, ref, existingHeader)
+		if err != nil {
+			return nil, err
+		}
+		missingHeaderHashes = append(missingHeaderHashes, missing...)
 	}
+	return missingHeaderHashes, nil
+}
 
-	for _, ref := range header.ExternalReferences {
-		header, err := s.db.GetObjectHeader(ref)
+func (s *Service) fetchAndSaveObject(r *retrieval, header model.ObjectHeader, objectHeaderHash string) error {
+	if s.interrupted.Err() != nil {
+		return errors.ErrDownloadInterrupted
+	}
+	hash := header.ObjectHash
+	// declared sizes of all headers are checked only once everything is retrieved, so retrieved bytes are counted
+	// to stop feed that declares less than it contains
+	if r.limit > 0 && r.retrieved+header.ObjectSize > r.limit {
+		return r.limitExceeded
+	}
+	var object model.Object
+	start := time.Now()
+	err := s.fetch(r.client, r.rootHash.Publisher, func(address string) error {
+		var err error
+		object, err = s.fetchObject(r.client, address, hash)
 		if err != nil {
-			return fmt.Errorf("retrieveing object header with hash: %v failed due to error: %v", ref, err)
+			return fmt.Errorf("fetch object with hash: %v failed due to error: %w", hash, err)
 		}
-		if err := s.UpdateObjectHeaderRootHashKey(ref, rootHashKey, header); err != nil {
-			return err
+		if err := parcel.CheckHash(object, hash); err != nil {
+			return fmt.Errorf("received invalid object: %v", err)
 		}
+		if err := parcel.CheckObjectSize(header, object); err != nil {
+			return fmt.Errorf("received object: %v that doesn't match its header: %v", hash, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	s.metrics.observeFetch(fetchObject, start)
+	s.metrics.objectsFetched.Inc()
+	s.metrics.bytesDownloaded.Add(float64(object.Length))
+	r.retrieved += object.Length
+
+	if err := s.db.SaveObject(hash, objectHeaderHash, object); err != nil {
+		return fmt.Errorf("saving object with hash: %v failed due to error: %v", hash, err)
+	}
+
 	return nil
 }
 
-func (s *Service) fetchObjectHeaders(client *http.Client, objectHeaderHashes ...string) ([]model.ObjectHeader, error) {
+func (s *Service) fetchObjectHeaders(client *http.Client, address string, objectHeaderHashes ...string) ([]model.ObjectHeader, error) {
 	objectHeadersResp := model.GetObjectHeadersResponse{}
 
-	baseUrl := fmt.Sprint(s.config.TrackerAddress, "/data/object/header?hash=", objectHeaderHashes[0])
+	baseUrl := fmt.Sprint(address, objectHeaderRoute, "?hash=", objectHeaderHashes[0])
 	additionalParams := ""
 	for _, hash := range objectHeaderHashes[1:] {
 		additionalParams = fmt.Sprint(additionalParams, "&hash=", hash)
@@ -237,17 +672,20 @@ func (s *Service) fetchObjectHeaders(client *http.Client, objectHeaderHashes ...
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return []model.ObjectHeader{}, fmt.Errorf("request for object headers with hashes: %v failed due to error: %v", objectHeaderHashes, err)
+		return []model.ObjectHeader{}, fmt.Errorf("request for object headers with hashes: %v failed due to error: %w", objectHeaderHashes, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			panic(err)
 		}
 	}()
+	if resp.StatusCode != http.StatusOK {
+		return []model.ObjectHeader{}, tracker.NewStatusError(fmt.Sprint("request for object headers with hashes: ", objectHeaderHashes), resp)
+	}
 
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return []model.ObjectHeader{}, fmt.Errorf("error reading data: %v", err)
+		return []model.ObjectHeader{}, fmt.Errorf("error reading data: %w", err)
 	}
 
 	if objectHeaderErr := json.Unmarshal(data, &objectHeadersResp); objectHeaderErr != nil {
@@ -257,9 +695,9 @@ func (s *Service) fetchObjectHeaders(client *http.Client, objectHeaderHashes ...
 	return objectHeadersResp.ObjectHeaders, nil
 }
 
-func (s *Service) fetchObject(client *http.Client, objectHash string) (model.Object, error) {
+func (s *Service) fetchObject(client *http.Client, address, objectHash string) (model.Object, error) {
 	object := model.Object{}
-	url := fmt.Sprint(s.config.TrackerAddress, "/data/object?hash=", objectHash)
+	url := fmt.Sprint(address, objectRoute, "?hash=", objectHash)
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -268,17 +706,20 @@ func (s *Service) fetchObject(client *http.Client, objectHash string) (model.Obj
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return object, fmt.Errorf("request for object failed due to error: %v", err)
+		return object, fmt.Errorf("request for object failed due to error: %w", err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			panic(err)
 		}
 	}()
+	if resp.StatusCode != http.StatusOK {
+		return object, tracker.NewStatusError(fmt.Sprint("request for object with hash: ", objectHash), resp)
+	}
 
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return object, fmt.Errorf("error reading data: %v", err)
+		return object, fmt.Errorf("error reading data: %w", err)
 	}
 
 	if objectErr := json.Unmarshal(data, &object); objectErr != nil {
@@ -288,46 +729,85 @@ func (s *Service) fetchObject(client *http.Client, objectHash string) (model.Obj
 	return object, nil
 }
 
-func (s *Service) checkSignature(rootHash model.RootHash) (model.Parcel, bool) {
-	parcel := model.Parcel{}
-	s.recreateParcel(&parcel, rootHash.ObjectHeaderHash)
+// checkSignatureVersion - refuse legacy signature unless it's allowed in config. Once publisher signs root hash,
+// its legacy signatures are refused anyway, so older parcel can't be replayed with higher sequence.
+func (s *Service) checkSignatureVersion(rootHash model.RootHash) error {
+	if err := signature.CheckVersion(rootHash, s.config.Trust.LegacySignatures); err != nil {
+		return err
+	}
+	if rootHash.SignatureVersion != signature.ParcelVersion {
+		return nil
+	}
 
-	parcelBytes, err := json.Marshal(parcel)
+	latest, err := s.db.GetLatestRootHash(rootHash.Publisher)
+	if err == errors.ErrCannotFindRootHash {
+		return nil
+	}
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("fetching latest root hash of publisher: %v failed due to error: %v", rootHash.Publisher, err)
 	}
+	if latest.SignatureVersion != signature.ParcelVersion {
+		return errors.ErrLegacySignature
+	}
+	return nil
+}
 
-	sig := dmsgcipher.Sig{}
-	_ = sig.UnmarshalText([]byte(rootHash.Signature))
-
-	pubKey := dmsgcipher.PubKey{}
-	_ = pubKey.UnmarshalText([]byte(rootHash.Publisher))
-
-	if err = dmsgcipher.VerifyPubKeySignedPayload(pubKey, sig, parcelBytes); err != nil {
-		fmt.Printf("parcel signature verification failed due to error: %v \n", err)
-		return parcel, false
+func (s *Service) checkSignature(rootHash model.RootHash) error {
+	if err := s.checkSignatureVersion(rootHash); err != nil {
+		return err
+	}
+	switch rootHash.SignatureVersion {
+	case signature.RootHashVersion:
+		if err := signature.VerifyRootHash(rootHash); err != nil {
+			return err
+		}
+		// signed object header hash covers every header and object as long as each of them matches its hash
+		if err := s.checkDAG(rootHash.ObjectHeaderHash, make(map[string]struct{})); err != nil {
+			return err
+		}
+		return parcel.CheckTreeSizes(s.db, rootHash.ObjectHeaderHash)
+	case signature.ParcelVersion:
+		// legacy signature covers the whole marshalled parcel so it has to be recreated in memory
+		feedParcel, err := parcel.Recreate(s.db, rootHash.ObjectHeaderHash)
+		if err != nil {
+			return err
+		}
+		return signature.VerifyParcel(rootHash, feedParcel)
+	default:
+		return fmt.Errorf("unsupported signature version: %v", rootHash.SignatureVersion)
 	}
-	return parcel, true
 }
 
-func (s *Service) recreateParcel(parcel *model.Parcel, hash string) {
+// checkDAG - make sure that header with given hash and everything it references is stored and matches its hash.
+// Objects are checked against their hashes when retrieved, so only headers are loaded and feed size doesn't matter.
+func (s *Service) checkDAG(hash string, checked map[string]struct{}) error {
+	if _, ok := checked[hash]; ok {
+		return nil
+	}
+
 	header, err := s.db.GetObjectHeader(hash)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("fetching object header with hash: %v failed due to error: %v", hash, err)
+	}
+	if err := parcel.CheckHash(header, hash); err != nil {
+		return fmt.Errorf("stored object header is corrupted: %v", err)
 	}
-	parcel.ObjectHeaders = append(parcel.ObjectHeaders, header)
-
-	if len(header.ObjectHash) == 0 {
-		for _, ref := range header.ExternalReferences {
-			s.recreateParcel(parcel, ref)
-		}
 
-	} else {
-		object, err := s.db.GetObject(header.ObjectHash)
+	if len(header.ObjectHash) > 0 {
+		exists, err := s.db.HasObject(header.ObjectHash)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("checking object with hash: %v failed due to error: %v", header.ObjectHash, err)
 		}
+		if !exists {
+			return fmt.Errorf("object with hash: %v referenced by object header: %v is missing", header.ObjectHash, hash)
+		}
+	}
 
-		parcel.Objects = append(parcel.Objects, object)
+	for _, ref := range header.ExternalReferences {
+		if err := s.checkDAG(ref, checked); err != nil {
+			return err
+		}
 	}
+	checked[hash] = struct{}{}
+	return nil
 }
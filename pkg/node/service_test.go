@@ -0,0 +1,124 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/errors"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/node/data"
+	"github.com/SkycoinProject/cxo-2/pkg/signature"
+	dmsghttp "github.com/SkycoinProject/dmsg-http"
+	"github.com/SkycoinProject/dmsg/disc"
+	log "github.com/sirupsen/logrus"
+)
+
+// newTestService - service with in-memory data store that isn't running, configured the same way as in end-to-end tests
+func newTestService(t *testing.T, cfg config.Config) *Service {
+	t.Helper()
+	logger := log.New()
+	logger.SetLevel(log.WarnLevel)
+	if cfg.Discovery == nil {
+		cfg.Discovery = disc.NewHTTP(dmsghttp.DefaultDiscoveryURL)
+	}
+	return NewService(cfg, data.NewMemoryData(logger), logger)
+}
+
+func TestValidateRootHash(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	latest := model.RootHash{
+		Publisher:        "publisher",
+		Signature:        "signature",
+		Sequence:         5,
+		Timestamp:        now.Add(-time.Hour),
+		ObjectHeaderHash: "objectHeaderHash",
+	}
+
+	tests := []struct {
+		name     string
+		rootHash model.RootHash
+		err      error
+	}{
+		{name: "first sequence of other publisher",
+			rootHash: model.RootHash{Publisher: "other", Sequence: 0, Timestamp: now}},
+		{name: "newer sequence",
+			rootHash: model.RootHash{Publisher: "publisher", Sequence: 6, Timestamp: now}},
+		{name: "same root hash", rootHash: latest},
+		{name: "older sequence", err: errors.ErrStaleSequence,
+			rootHash: model.RootHash{Publisher: "publisher", Sequence: 4, Timestamp: now}},
+		{name: "same sequence with other data", err: errors.ErrForkedSequence,
+			rootHash: model.RootHash{Publisher: "publisher", Signature: "signature", Sequence: 5,
+				Timestamp: latest.Timestamp, ObjectHeaderHash: "otherObjectHeaderHash"}},
+		{name: "same sequence with other signature", err: errors.ErrForkedSequence,
+			rootHash: model.RootHash{Publisher: "publisher", Signature: "otherSignature", Sequence: 5,
+				Timestamp: latest.Timestamp, ObjectHeaderHash: "objectHeaderHash"}},
+		{name: "same sequence with other timestamp", err: errors.ErrForkedSequence,
+			rootHash: model.RootHash{Publisher: "publisher", Signature: "signature", Sequence: 5,
+				Timestamp: now, ObjectHeaderHash: "objectHeaderHash"}},
+		{name: "timestamp within allowed drift",
+			rootHash: model.RootHash{Publisher: "publisher", Sequence: 6, Timestamp: now.Add(maxTimestampDrift / 2)}},
+		{name: "timestamp too far in the future", err: errors.ErrTimestampInFuture,
+			rootHash: model.RootHash{Publisher: "publisher", Sequence: 6, Timestamp: now.Add(2 * maxTimestampDrift)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestService(t, config.Config{})
+			if err := s.db.SaveRootHash(latest); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.validateRootHash(tc.rootHash); err != tc.err {
+				t.Fatalf("expected error: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestCheckSignatureRejectsUnknownVersion(t *testing.T) {
+	s := newTestService(t, config.Config{})
+	rootHash := model.RootHash{
+		Publisher:        "publisher",
+		Signature:        "signature",
+		SignatureVersion: signature.RootHashVersion + 1,
+		ObjectHeaderHash: "objectHeaderHash",
+	}
+	if err := s.checkSignature(rootHash); err == nil {
+		t.Fatal("expected signature of unknown version to be rejected")
+	}
+}
+
+func TestCheckSignatureVersion(t *testing.T) {
+	legacy := model.RootHash{Publisher: "publisher", SignatureVersion: signature.ParcelVersion, Sequence: 6}
+	signed := model.RootHash{Publisher: "publisher", SignatureVersion: signature.RootHashVersion, Sequence: 6}
+
+	tests := []struct {
+		name             string
+		stored           *model.RootHash
+		legacySignatures bool
+		rootHash         model.RootHash
+		err              error
+	}{
+		{name: "root hash signature", rootHash: signed},
+		{name: "legacy signature refused by default", rootHash: legacy, err: errors.ErrLegacySignature},
+		{name: "legacy signature allowed in config", legacySignatures: true, rootHash: legacy},
+		{name: "legacy signature after legacy one", legacySignatures: true, rootHash: legacy,
+			stored: &model.RootHash{Publisher: "publisher", SignatureVersion: signature.ParcelVersion, Sequence: 5}},
+		// publisher that signs root hash can't be impersonated by replaying its older parcel with higher sequence
+		{name: "legacy signature after root hash signature", legacySignatures: true, rootHash: legacy,
+			stored: &model.RootHash{Publisher: "publisher", SignatureVersion: signature.RootHashVersion, Sequence: 5},
+			err:    errors.ErrLegacySignature},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestService(t, config.Config{Trust: config.TrustConfig{LegacySignatures: tc.legacySignatures}})
+			if tc.stored != nil {
+				if err := s.db.SaveRootHash(*tc.stored); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.checkSignatureVersion(tc.rootHash); err != tc.err {
+				t.Fatalf("expected error: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package node
+
+import (
+	"fmt"
+
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/transport"
+)
+
+// names of node checks
+const (
+	databaseCheck = "database"
+	serverCheck   = "server"
+	trackersCheck = "trackers"
+	shutdownCheck = "shutdown"
+)
+
+// Health - whether node is alive: data store is open and node server accepts connections
+func (s *Service) Health() model.Health {
+	checks := make(map[string]string)
+	checks[databaseCheck] = checkResult(s.db.Ping())
+
+	var err error
+	if s.server == nil || !s.server.Listening() {
+		err = fmt.Errorf("%v server is not listening", s.config.Server.Transport)
+	}
+	checks[serverCheck] = checkResult(err)
+	return health(checks)
+}
+
+// Readiness - whether node can receive and retrieve data: it's healthy, at least one tracker responded to the
+// last request or health check and node isn't shutting down
+func (s *Service) Readiness() model.Health {
+	checks := s.Health().Checks
+
+	err := fmt.Errorf("no tracker is reachable")
+	for _, tracker := range s.trackers.Statuses() {
+		if tracker.Healthy && !tracker.CheckedAt.IsZero() {
+			err = nil
+			break
+		}
+	}
+	checks[trackersCheck] = checkResult(err)
+
+	err = nil
+	if s.requests.isClosed() {
+		err = fmt.Errorf("node is shutting down")
+	}
+	checks[shutdownCheck] = checkResult(err)
+	return health(checks)
+}
+
+// Status - summary of node readiness, trackers, stored feeds and downloads that didn't finish yet
+func (s *Service) Status() (model.NodeStatus, error) {
+	feeds, err := s.FeedStatuses()
+	if err != nil {
+		return model.NodeStatus{}, err
+	}
+	downloads, err := s.db.GetUnfinishedDownloads()
+	if err != nil {
+		return model.NodeStatus{}, fmt.Errorf("fetching unfinished downloads failed due to error: %v", err)
+	}
+
+	return model.NodeStatus{
+		PubKey:    s.config.PubKey.Hex(),
+		Transport: s.config.Server.Transport,
+		Address:   transport.Address(s.config),
+		Health:    s.Readiness(),
+		Trackers:  s.trackers.Statuses(),
+		Feeds:     feeds,
+		Downloads: downloads,
+		Queue:     s.queue.status(),
+	}, nil
+}
+
+func checkResult(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return model.HealthOK
+}
+
+func health(checks map[string]string) model.Health {
+	status := model.HealthOK
+	for _, result := range checks {
+		if result != model.HealthOK {
+			status = model.HealthFailing
+		}
+	}
+	return model.Health{Status: status, Checks: checks}
+}
@@ -0,0 +1,86 @@
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/transport"
+)
+
+// TestNotifyOverHTTPTransport - trackers reached over HTTP notify the node through its HTTP server, which knows
+// them only by IP address of the connection
+func TestNotifyOverHTTPTransport(t *testing.T) {
+	req := newTestFeed(t, testDirectory("shared", testEntry("first", "first content")))
+
+	tests := []struct {
+		name         string
+		trackerHosts []string
+		status       int
+	}{
+		// stored root hash is accepted as duplicate, without retrieving anything
+		{name: "trusted tracker host", trackerHosts: []string{"127.0.0.1"}, status: http.StatusOK},
+		{name: "untrusted tracker host", trackerHosts: []string{"10.1.2.3"}, status: http.StatusForbidden},
+		{name: "no tracker hosts", status: http.StatusForbidden},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			address := listener.Addr().String()
+			_ = listener.Close()
+
+			cfg := config.Config{
+				Server: config.ServerConfig{Transport: config.HTTPTransport, Address: address},
+				Trust: config.TrustConfig{
+					TrackerHosts:  tc.trackerHosts,
+					Subscriptions: []string{req.RootHash.Publisher},
+				},
+			}
+			s := newTestService(t, cfg)
+			storeTestFeed(t, s, req)
+
+			server := transport.NewServer(cfg)
+			mux := http.NewServeMux()
+			mux.HandleFunc(notifyRoute, s.notifyHandler)
+			served := make(chan error, 1)
+			go func() { served <- server.Serve(mux) }()
+			defer func() {
+				if err := server.Close(); err != nil {
+					t.Error(err)
+				}
+				if err := <-served; err != nil {
+					t.Errorf("expected closed server to stop without error, got: %v", err)
+				}
+			}()
+			deadline := time.Now().Add(5 * time.Second)
+			for !server.Listening() {
+				if time.Now().After(deadline) {
+					t.Fatal("server isn't listening")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			body, err := json.Marshal(req.RootHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// node is reached the same way trackers reach it, on the address it subscribes with
+			client := transport.NewClient(cfg.Discovery, cfg.PubKey, cfg.SecKey)
+			resp, err := client.Post(transport.Address(cfg)+notifyRoute, "application/json", bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tc.status {
+				t.Fatalf("expected status: %v, got: %v", tc.status, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package node
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/errors"
+	"github.com/SkycoinProject/cxo-2/pkg/logging"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// authenticateTracker - check that peer with given remote address is one of the trusted trackers and return its
+// public key, or its host for trackers reached over HTTP. Dmsg peers are identified by <public key>:<port>, which
+// can't be forged since dmsg connections are encrypted with keys of both peers. HTTP peers are identified only by
+// IP address, which has to match one of the addresses of configured HTTP trackers.
+func (s *Service) authenticateTracker(remoteAddr string) (string, error) {
+	host := remoteAddr
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = strings.Trim(host[:i], "[]")
+	}
+
+	var pubKey cipher.PubKey
+	if err := pubKey.UnmarshalText([]byte(host)); err == nil {
+		for _, tracker := range s.config.Trust.Trackers {
+			if tracker == pubKey {
+				return pubKey.Hex(), nil
+			}
+		}
+		return "", errors.ErrUntrustedTracker
+	}
+
+	for _, trackerHost := range s.config.Trust.TrackerHosts {
+		addresses, err := s.trackerHosts.resolve(trackerHost)
+		if err != nil {
+			s.logger.WithField(logging.TrackerField, trackerHost).WithError(err).Debug("Resolving tracker host failed")
+			continue
+		}
+		if contains(addresses, host) {
+			return trackerHost, nil
+		}
+	}
+	return "", errors.ErrUntrustedTracker
+}
+
+// trackerHostsTTL - how long addresses of tracker hosts are used before the hosts are resolved again
+const trackerHostsTTL = 5 * time.Minute
+
+// hostResolver - addresses of tracker hosts, cached so that notifications aren't delayed by resolving hosts
+type hostResolver struct {
+	mux    sync.Mutex
+	hosts  map[string]resolvedHost
+	lookup func(host string) ([]string, error)
+}
+
+type resolvedHost struct {
+	addresses  []string
+	resolvedAt time.Time
+}
+
+func newHostResolver() *hostResolver {
+	return &hostResolver{hosts: make(map[string]resolvedHost), lookup: net.LookupHost}
+}
+
+// resolve - addresses of the host, looked up again once they are older than trackerHostsTTL. Failed lookup isn't
+// cached, so it's retried with the next notification.
+func (r *hostResolver) resolve(host string) ([]string, error) {
+	r.mux.Lock()
+	resolved, ok := r.hosts[host]
+	r.mux.Unlock()
+	if ok && time.Since(resolved.resolvedAt) < trackerHostsTTL {
+		return resolved.addresses, nil
+	}
+
+	addresses, err := r.lookup(host)
+	if err != nil {
+		return nil, err
+	}
+	r.mux.Lock()
+	r.hosts[host] = resolvedHost{addresses: addresses, resolvedAt: time.Now()}
+	r.mux.Unlock()
+	return addresses, nil
+}
+
+// checkPublisherTrusted - refuse root hashes of blocked publishers and of publishers node isn't subscribed to.
+// Block list wins over subscriptions, so publisher can be blocked without losing its subscription.
+func (s *Service) checkPublisherTrusted(publisher string) error {
+	if err := s.checkPublisherNotBlocked(publisher); err != nil {
+		return err
+	}
+
+	if contains(s.config.Trust.Subscriptions, publisher) {
+		return nil
+	}
+	_, err := s.db.GetSubscription(publisher)
+	if err == errors.ErrCannotFindSubscription {
+		return errors.ErrNotSubscribed
+	}
+	if err != nil {
+		return fmt.Errorf("fetching subscription of publisher: %v failed due to error: %v", publisher, err)
+	}
+	return nil
+}
+
+func (s *Service) checkPublisherNotBlocked(publisher string) error {
+	if contains(s.config.Trust.BlockedPublishers, publisher) {
+		return errors.ErrPublisherBlocked
+	}
+	_, err := s.db.GetBlockedPublisher(publisher)
+	if err == nil {
+		return errors.ErrPublisherBlocked
+	}
+	if err != errors.ErrCannotFindBlocked {
+		return fmt.Errorf("fetching blocked publisher: %v failed due to error: %v", publisher, err)
+	}
+	return nil
+}
+
+// Subscribe - accept root hashes of the publisher
+func (s *Service) Subscribe(publisher string) error {
+	if err := validatePublicKey(publisher); err != nil {
+		return err
+	}
+	if _, err := s.db.GetSubscription(publisher); err == nil {
+		return nil
+	}
+	s.logger.WithField(logging.PublisherField, publisher).Info("Subscribing to publisher")
+	return s.db.SaveSubscription(model.Subscription{Publisher: publisher, CreatedAt: time.Now()})
+}
+
+// Unsubscribe - stop accepting root hashes of the publisher, data already stored is kept
+func (s *Service) Unsubscribe(publisher string) error {
+	if contains(s.config.Trust.Subscriptions, publisher) {
+		return errors.ErrConfiguredPublisher
+	}
+	s.logger.WithField(logging.PublisherField, publisher).Info("Unsubscribing from publisher")
+	return s.db.RemoveSubscription(publisher)
+}
+
+// Block - refuse root hashes of the publisher even if it's subscribed
+func (s *Service) Block(publisher string) error {
+	if err := validatePublicKey(publisher); err != nil {
+		return err
+	}
+	if _, err := s.db.GetBlockedPublisher(publisher); err == nil {
+		return nil
+	}
+	s.logger.WithField(logging.PublisherField, publisher).Info("Blocking publisher")
+	return s.db.SaveBlockedPublisher(model.BlockedPublisher{Publisher: publisher, CreatedAt: time.Now()})
+}
+
+// Unblock - remove publisher from block list, its root hashes are accepted again only if it's subscribed
+func (s *Service) Unblock(publisher string) error {
+	if contains(s.config.Trust.BlockedPublishers, publisher) {
+		return errors.ErrConfiguredPublisher
+	}
+	s.logger.WithField(logging.PublisherField, publisher).Info("Unblocking publisher")
+	return s.db.RemoveBlockedPublisher(publisher)
+}
+
+// Subscriptions - publishers subscribed through the CLI or in config file
+func (s *Service) Subscriptions() ([]model.Subscription, error) {
+	stored, err := s.db.GetAllSubscriptions()
+	if err != nil {
+		return nil, fmt.Errorf("fetching subscriptions failed due to error: %v", err)
+	}
+
+	subscriptions := make(map[string]model.Subscription)
+	for _, subscription := range stored {
+		subscriptions[subscription.Publisher] = subscription
+	}
+	for _, publisher := range s.config.Trust.Subscriptions {
+		subscription := subscriptions[publisher]
+		subscription.Publisher = publisher
+		subscription.Configured = true
+		subscriptions[publisher] = subscription
+	}
+
+	result := make([]model.Subscription, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		result = append(result, subscription)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Publisher < result[j].Publisher })
+	return result, nil
+}
+
+// BlockedPublishers - publishers blocked through the CLI or in config file
+func (s *Service) BlockedPublishers() ([]model.BlockedPublisher, error) {
+	stored, err := s.db.GetAllBlockedPublishers()
+	if err != nil {
+		return nil, fmt.Errorf("fetching blocked publishers failed due to error: %v", err)
+	}
+
+	blocked := make(map[string]model.BlockedPublisher)
+	for _, publisher := range stored {
+		blocked[publisher.Publisher] = publisher
+	}
+	for _, publisher := range s.config.Trust.BlockedPublishers {
+		entry := blocked[publisher]
+		entry.Publisher = publisher
+		entry.Configured = true
+		blocked[publisher] = entry
+	}
+
+	result := make([]model.BlockedPublisher, 0, len(blocked))
+	for _, publisher := range blocked {
+		result = append(result, publisher)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Publisher < result[j].Publisher })
+	return result, nil
+}
+
+func validatePublicKey(publisher string) error {
+	var pubKey cipher.PubKey
+	if err := pubKey.UnmarshalText([]byte(publisher)); err != nil || pubKey.Null() {
+		return errors.ErrInvalidPublicKey
+	}
+	return nil
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
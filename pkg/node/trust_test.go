@@ -0,0 +1,85 @@
+package node
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/errors"
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+func TestAuthenticateTracker(t *testing.T) {
+	trusted, _ := cipher.GenerateKeyPair()
+	untrusted, _ := cipher.GenerateKeyPair()
+	s := newTestService(t, config.Config{
+		Trust: config.TrustConfig{
+			Trackers:     []cipher.PubKey{trusted},
+			TrackerHosts: []string{"127.0.0.1", "::1"},
+		},
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		tracker    string
+		err        error
+	}{
+		{name: "trusted dmsg tracker", remoteAddr: trusted.Hex() + ":8084", tracker: trusted.Hex()},
+		{name: "untrusted dmsg tracker", remoteAddr: untrusted.Hex() + ":8084", err: errors.ErrUntrustedTracker},
+		{name: "trusted HTTP tracker", remoteAddr: "127.0.0.1:51234", tracker: "127.0.0.1"},
+		{name: "trusted HTTP tracker over IPv6", remoteAddr: "[::1]:51234", tracker: "::1"},
+		{name: "untrusted HTTP tracker", remoteAddr: "10.1.2.3:51234", err: errors.ErrUntrustedTracker},
+		// configured host has to match the whole address, not just its prefix
+		{name: "HTTP tracker with similar address", remoteAddr: "127.0.0.10:51234", err: errors.ErrUntrustedTracker},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker, err := s.authenticateTracker(tc.remoteAddr)
+			if err != tc.err {
+				t.Fatalf("expected error: %v, got: %v", tc.err, err)
+			}
+			if tracker != tc.tracker {
+				t.Fatalf("expected tracker: %v, got: %v", tc.tracker, tracker)
+			}
+		})
+	}
+}
+
+func TestHostResolver(t *testing.T) {
+	s := newTestService(t, config.Config{Trust: config.TrustConfig{TrackerHosts: []string{"tracker", "unresolved"}}})
+	lookups := make(map[string]int)
+	s.trackerHosts.lookup = func(host string) ([]string, error) {
+		lookups[host]++
+		if host == "unresolved" {
+			return nil, fmt.Errorf("no such host: %v", host)
+		}
+		return []string{"10.0.0.1", "10.0.0.2"}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if tracker, err := s.authenticateTracker("10.0.0.2:51234"); err != nil || tracker != "tracker" {
+			t.Fatalf("expected tracker to be authenticated, got: %v, error: %v", tracker, err)
+		}
+		if _, err := s.authenticateTracker("10.0.0.3:51234"); err != errors.ErrUntrustedTracker {
+			t.Fatalf("expected error: %v, got: %v", errors.ErrUntrustedTracker, err)
+		}
+	}
+	// failed lookup isn't cached, so every notification not accepted by the earlier host retries it
+	if lookups["tracker"] != 1 || lookups["unresolved"] != 3 {
+		t.Fatalf("expected resolved host to be looked up once and unresolved one every time, got: %v", lookups)
+	}
+
+	// addresses of the host can change, so they are looked up again once they expire
+	s.trackerHosts.hosts["tracker"] = resolvedHost{
+		addresses:  []string{"10.0.0.1"},
+		resolvedAt: time.Now().Add(-trackerHostsTTL),
+	}
+	if tracker, err := s.authenticateTracker("10.0.0.2:51234"); err != nil || tracker != "tracker" {
+		t.Fatalf("expected tracker to be authenticated with addresses looked up again, got: %v, error: %v", tracker, err)
+	}
+	if lookups["tracker"] != 2 {
+		t.Fatalf("expected expired host to be looked up again, got %v lookups", lookups["tracker"])
+	}
+}
@@ -0,0 +1,62 @@
+package parcel
+
+import (
+	"fmt"
+
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/util"
+)
+
+// Entry - data to be published as single object header, with its own object if it has data, referencing
+// object headers built from its children
+type Entry struct {
+	Meta     []model.Meta
+	Data     []byte
+	Children []Entry
+}
+
+// Build - create parcel from entry and all of its children, with every hash and size field computed.
+// Headers are ordered the same way as in parcel recreated by nodes, root header first. Hash of the root header
+// is returned as well, to be referenced by root hash.
+func Build(root Entry) (model.Parcel, string, error) {
+	parcel := model.Parcel{}
+	hash, _, err := build(&parcel, root)
+	return parcel, hash, err
+}
+
+func build(parcel *model.Parcel, entry Entry) (string, model.ObjectHeader, error) {
+	header := model.ObjectHeader{Meta: entry.Meta}
+	// header has to be hashed after all of its children, but it precedes them in the parcel
+	index := len(parcel.ObjectHeaders)
+	parcel.ObjectHeaders = append(parcel.ObjectHeaders, header)
+
+	var objectLength uint64
+	if entry.Data != nil {
+		object := model.Object{Length: uint64(len(entry.Data)), Data: entry.Data}
+		objectHash, err := util.SHA256(object)
+		if err != nil {
+			return "", model.ObjectHeader{}, fmt.Errorf("hashing object failed due to error: %v", err)
+		}
+		header.ObjectHash = objectHash
+		objectLength = object.Length
+		parcel.Objects = append(parcel.Objects, object)
+	}
+
+	refs := make([]model.ObjectHeader, 0, len(entry.Children))
+	for _, child := range entry.Children {
+		childHash, childHeader, err := build(parcel, child)
+		if err != nil {
+			return "", model.ObjectHeader{}, err
+		}
+		header.ExternalReferences = append(header.ExternalReferences, childHash)
+		refs = append(refs, childHeader)
+	}
+	ComputeSizes(objectLength, refs).apply(&header)
+
+	hash, err := util.SHA256(header)
+	if err != nil {
+		return "", model.ObjectHeader{}, fmt.Errorf("hashing object header failed due to error: %v", err)
+	}
+	parcel.ObjectHeaders[index] = header
+	return hash, header, nil
+}
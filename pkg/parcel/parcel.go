@@ -0,0 +1,100 @@
+// Package parcel - working with feed data as a whole, independently of where headers and objects are stored
+package parcel
+
+import (
+	"fmt"
+
+	"github.com/SkycoinProject/cxo-2/pkg/errors"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/util"
+)
+
+// Source - object headers and objects addressed by their hashes
+type Source interface {
+	GetObjectHeader(hash string) (model.ObjectHeader, error)
+	GetObject(hash string) (model.Object, error)
+}
+
+// Feed - root hash with its data held in memory, keyed by hashes
+type Feed struct {
+	RootHash      model.RootHash
+	ObjectHeaders map[string]model.ObjectHeader
+	Objects       map[string]model.Object
+	// parcel the feed was created from, if any
+	parcel *model.Parcel
+}
+
+// NewFeed - create feed from publish data request, headers and objects are keyed by their computed hashes
+func NewFeed(req model.PublishDataRequest) (Feed, error) {
+	feed := Feed{
+		RootHash:      req.RootHash,
+		ObjectHeaders: make(map[string]model.ObjectHeader, len(req.Parcel.ObjectHeaders)),
+		Objects:       make(map[string]model.Object, len(req.Parcel.Objects)),
+		parcel:        &req.Parcel,
+	}
+	for _, header := range req.Parcel.ObjectHeaders {
+		hash, err := util.SHA256(header)
+		if err != nil {
+			return Feed{}, fmt.Errorf("hashing object header failed due to error: %v", err)
+		}
+		feed.ObjectHeaders[hash] = header
+	}
+	for _, object := range req.Parcel.Objects {
+		hash, err := util.SHA256(object)
+		if err != nil {
+			return Feed{}, fmt.Errorf("hashing object failed due to error: %v", err)
+		}
+		feed.Objects[hash] = object
+	}
+	return feed, nil
+}
+
+// GetObjectHeader - object header with given hash
+func (f Feed) GetObjectHeader(hash string) (model.ObjectHeader, error) {
+	header, ok := f.ObjectHeaders[hash]
+	if !ok {
+		return model.ObjectHeader{}, errors.ErrCannotFindObjectHeader
+	}
+	return header, nil
+}
+
+// GetObject - object with given hash
+func (f Feed) GetObject(hash string) (model.Object, error) {
+	object, ok := f.Objects[hash]
+	if !ok {
+		return model.Object{}, errors.ErrCannotFindObject
+	}
+	return object, nil
+}
+
+// Recreate - collect header with given hash and everything it references into parcel, in the order publisher
+// created it: each header is followed by its object, or by headers it references
+func Recreate(source Source, hash string) (model.Parcel, error) {
+	parcel := model.Parcel{}
+	err := appendToParcel(source, &parcel, hash)
+	return parcel, err
+}
+
+func appendToParcel(source Source, parcel *model.Parcel, hash string) error {
+	header, err := source.GetObjectHeader(hash)
+	if err != nil {
+		return fmt.Errorf("fetching object header with hash: %v failed due to error: %v", hash, err)
+	}
+	parcel.ObjectHeaders = append(parcel.ObjectHeaders, header)
+
+	if len(header.ObjectHash) == 0 {
+		for _, ref := range header.ExternalReferences {
+			if err := appendToParcel(source, parcel, ref); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	object, err := source.GetObject(header.ObjectHash)
+	if err != nil {
+		return fmt.Errorf("fetching object with hash: %v failed due to error: %v", header.ObjectHash, err)
+	}
+	parcel.Objects = append(parcel.Objects, object)
+	return nil
+}
@@ -0,0 +1,128 @@
+package parcel
+
+import (
+	"fmt"
+
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+)
+
+// Sizes - size fields of object header, derived from its object and headers it references:
+// ObjectSize is length of the header's own object, ExternalReferencesSize is number of referenced headers,
+// RecursiveSizeFirstLevel is sum of object sizes of referenced headers, RecursiveSizeTotal is sum of object sizes
+// of all headers reachable through references and Size is ObjectSize together with RecursiveSizeTotal.
+type Sizes struct {
+	ObjectSize              uint64
+	ExternalReferencesSize  uint64
+	RecursiveSizeFirstLevel uint64
+	RecursiveSizeTotal      uint64
+	Size                    uint64
+}
+
+// ComputeSizes - size fields of header with object of given length, referencing given headers
+func ComputeSizes(objectLength uint64, refs []model.ObjectHeader) Sizes {
+	sizes := Sizes{
+		ObjectSize:             objectLength,
+		ExternalReferencesSize: uint64(len(refs)),
+	}
+	for _, ref := range refs {
+		sizes.RecursiveSizeFirstLevel += ref.ObjectSize
+		sizes.RecursiveSizeTotal += ref.ObjectSize + ref.RecursiveSizeTotal
+	}
+	sizes.Size = sizes.ObjectSize + sizes.RecursiveSizeTotal
+	return sizes
+}
+
+func (s Sizes) apply(header *model.ObjectHeader) {
+	header.ObjectSize = s.ObjectSize
+	header.ExternalReferencesSize = s.ExternalReferencesSize
+	header.RecursiveSizeFirstLevel = s.RecursiveSizeFirstLevel
+	header.RecursiveSizeTotal = s.RecursiveSizeTotal
+	header.Size = s.Size
+}
+
+// TotalSize - number of bytes of all objects reachable from the header, as declared by the header itself
+func TotalSize(header model.ObjectHeader) uint64 {
+	if header.Size > 0 {
+		return header.Size
+	}
+	// size wasn't set by older publishers
+	return header.ObjectSize + header.RecursiveSizeTotal
+}
+
+// recursiveSizeMismatches - recursive size fields of header that differ from expected sizes. Other fields are
+// checked by CheckHeaderSizes and CheckObjectSize.
+func recursiveSizeMismatches(header model.ObjectHeader, expected Sizes) []error {
+	var mismatches []error
+	if header.RecursiveSizeFirstLevel != expected.RecursiveSizeFirstLevel {
+		mismatches = append(mismatches, fmt.Errorf("recursiveSizeFirstLevel is %v but referenced objects have %v",
+			header.RecursiveSizeFirstLevel, expected.RecursiveSizeFirstLevel))
+	}
+	if header.RecursiveSizeTotal != expected.RecursiveSizeTotal {
+		mismatches = append(mismatches, fmt.Errorf("recursiveSizeTotal is %v but all reachable objects have %v",
+			header.RecursiveSizeTotal, expected.RecursiveSizeTotal))
+	}
+	return mismatches
+}
+
+// CheckHeaderSizes - check size fields of header that don't depend on other data, so it can be done as soon as
+// header is received. Size is checked only if it's set, since publishers didn't set it before.
+func CheckHeaderSizes(header model.ObjectHeader) error {
+	if header.ExternalReferencesSize != uint64(len(header.ExternalReferences)) {
+		return fmt.Errorf("externalReferencesSize is %v but header references %v object headers",
+			header.ExternalReferencesSize, len(header.ExternalReferences))
+	}
+	if len(header.ObjectHash) == 0 && header.ObjectSize != 0 {
+		return fmt.Errorf("objectSize is %v but header has no object", header.ObjectSize)
+	}
+	if header.Size != 0 && header.Size != header.ObjectSize+header.RecursiveSizeTotal {
+		return fmt.Errorf("size is %v but objectSize and recursiveSizeTotal add up to %v",
+			header.Size, header.ObjectSize+header.RecursiveSizeTotal)
+	}
+	return nil
+}
+
+// CheckObjectSize - check that object has the size declared by its header
+func CheckObjectSize(header model.ObjectHeader, object model.Object) error {
+	if object.Length != uint64(len(object.Data)) {
+		return fmt.Errorf("object length is %v but it contains %v bytes", object.Length, len(object.Data))
+	}
+	if header.ObjectSize != object.Length {
+		return fmt.Errorf("objectSize is %v but object length is %v", header.ObjectSize, object.Length)
+	}
+	return nil
+}
+
+// CheckTreeSizes - check recursive size fields of header with given hash and all headers it references. Objects
+// are not loaded, their sizes are expected to be checked by CheckObjectSize when they are received.
+func CheckTreeSizes(source Source, hash string) error {
+	return checkTreeSizes(source, hash, make(map[string]struct{}))
+}
+
+func checkTreeSizes(source Source, hash string, checked map[string]struct{}) error {
+	if _, ok := checked[hash]; ok {
+		return nil
+	}
+
+	header, err := source.GetObjectHeader(hash)
+	if err != nil {
+		return fmt.Errorf("fetching object header with hash: %v failed due to error: %v", hash, err)
+	}
+
+	refs := make([]model.ObjectHeader, 0, len(header.ExternalReferences))
+	for _, ref := range header.ExternalReferences {
+		if err := checkTreeSizes(source, ref, checked); err != nil {
+			return err
+		}
+		refHeader, err := source.GetObjectHeader(ref)
+		if err != nil {
+			return fmt.Errorf("fetching object header with hash: %v failed due to error: %v", ref, err)
+		}
+		refs = append(refs, refHeader)
+	}
+
+	if mismatches := recursiveSizeMismatches(header, ComputeSizes(header.ObjectSize, refs)); len(mismatches) > 0 {
+		return fmt.Errorf("object header: %v has invalid size fields: %v", hash, mismatches[0])
+	}
+	checked[hash] = struct{}{}
+	return nil
+}
@@ -0,0 +1,211 @@
+package parcel
+
+import (
+	"testing"
+
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/util"
+)
+
+func TestComputeSizes(t *testing.T) {
+	leaf := model.ObjectHeader{ObjectSize: 3, Size: 3}
+	dir := model.ObjectHeader{ObjectSize: 2, RecursiveSizeFirstLevel: 6, RecursiveSizeTotal: 10, Size: 12}
+
+	tests := []struct {
+		name         string
+		objectLength uint64
+		refs         []model.ObjectHeader
+		expected     Sizes
+	}{
+		{name: "empty header"},
+		{name: "header with object", objectLength: 5, expected: Sizes{ObjectSize: 5, Size: 5}},
+		{name: "header referencing headers", refs: []model.ObjectHeader{leaf, leaf},
+			expected: Sizes{ExternalReferencesSize: 2, RecursiveSizeFirstLevel: 6, RecursiveSizeTotal: 6, Size: 6}},
+		// first level counts only objects of referenced headers, total counts everything they reference too
+		{name: "nested references", objectLength: 1, refs: []model.ObjectHeader{leaf, dir},
+			expected: Sizes{ObjectSize: 1, ExternalReferencesSize: 2, RecursiveSizeFirstLevel: 5, RecursiveSizeTotal: 15,
+				Size: 16}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if sizes := ComputeSizes(tc.objectLength, tc.refs); sizes != tc.expected {
+				t.Fatalf("expected sizes: %+v, got: %+v", tc.expected, sizes)
+			}
+		})
+	}
+}
+
+func TestCheckHeaderSizes(t *testing.T) {
+	tests := []struct {
+		name   string
+		header model.ObjectHeader
+		valid  bool
+	}{
+		{name: "header with object", header: model.ObjectHeader{ObjectHash: "hash", ObjectSize: 4, Size: 4}, valid: true},
+		{name: "header with references", valid: true, header: model.ObjectHeader{ExternalReferences: []string{"a", "b"},
+			ExternalReferencesSize: 2, RecursiveSizeFirstLevel: 3, RecursiveSizeTotal: 5, Size: 5}},
+		// size wasn't set by older publishers
+		{name: "header without size", header: model.ObjectHeader{ObjectHash: "hash", ObjectSize: 4}, valid: true},
+		{name: "wrong number of references", header: model.ObjectHeader{ExternalReferences: []string{"a", "b"},
+			ExternalReferencesSize: 1}},
+		{name: "object size without object", header: model.ObjectHeader{ObjectSize: 4, Size: 4}},
+		{name: "wrong total size", header: model.ObjectHeader{ObjectHash: "hash", ObjectSize: 4, RecursiveSizeTotal: 2,
+			Size: 4}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckHeaderSizes(tc.header)
+			if tc.valid && err != nil {
+				t.Fatalf("expected valid sizes, got error: %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Fatal("expected invalid sizes to be refused")
+			}
+		})
+	}
+}
+
+func TestCheckObjectSize(t *testing.T) {
+	tests := []struct {
+		name   string
+		header model.ObjectHeader
+		object model.Object
+		valid  bool
+	}{
+		{name: "matching sizes", header: model.ObjectHeader{ObjectSize: 4}, object: model.Object{Length: 4,
+			Data: []byte("data")}, valid: true},
+		{name: "wrong object size", header: model.ObjectHeader{ObjectSize: 5}, object: model.Object{Length: 4,
+			Data: []byte("data")}},
+		{name: "wrong object length", header: model.ObjectHeader{ObjectSize: 5}, object: model.Object{Length: 5,
+			Data: []byte("data")}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckObjectSize(tc.header, tc.object)
+			if tc.valid && err != nil {
+				t.Fatalf("expected valid sizes, got error: %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Fatal("expected invalid sizes to be refused")
+			}
+		})
+	}
+}
+
+func TestCheckTreeSizes(t *testing.T) {
+	tests := []struct {
+		name string
+		// tamper - change header of the built feed, it's kept under its original hash
+		tamper func(feed Feed, root model.ObjectHeader)
+		valid  bool
+	}{
+		{name: "built feed", valid: true},
+		{name: "wrong first level size", tamper: func(feed Feed, root model.ObjectHeader) {
+			header := feed.ObjectHeaders[root.ExternalReferences[0]]
+			header.RecursiveSizeFirstLevel++
+			feed.ObjectHeaders[root.ExternalReferences[0]] = header
+		}},
+		{name: "wrong total size", tamper: func(feed Feed, root model.ObjectHeader) {
+			header := feed.ObjectHeaders[root.ExternalReferences[0]]
+			header.RecursiveSizeTotal--
+			feed.ObjectHeaders[root.ExternalReferences[0]] = header
+		}},
+		// referencing headers declare sizes that no longer match the changed object size
+		{name: "wrong object size of referenced header", tamper: func(feed Feed, root model.ObjectHeader) {
+			nested := feed.ObjectHeaders[root.ExternalReferences[0]]
+			header := feed.ObjectHeaders[nested.ExternalReferences[0]]
+			header.ObjectSize++
+			header.Size++
+			feed.ObjectHeaders[nested.ExternalReferences[0]] = header
+		}},
+		{name: "missing referenced header", tamper: func(feed Feed, root model.ObjectHeader) {
+			delete(feed.ObjectHeaders, root.ExternalReferences[1])
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			feed, hash := buildTestFeed(t)
+			if tc.tamper != nil {
+				tc.tamper(feed, feed.ObjectHeaders[hash])
+			}
+			err := CheckTreeSizes(feed, hash)
+			if tc.valid && err != nil {
+				t.Fatalf("expected valid sizes, got error: %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Fatal("expected invalid sizes to be refused")
+			}
+		})
+	}
+}
+
+// TestBuildSizes - sizes of every header built for publishing match sizes computed from its object and references
+func TestBuildSizes(t *testing.T) {
+	feed, hash := buildTestFeed(t)
+	root := feed.ObjectHeaders[hash]
+	if root.Size != uint64(len("first")+len("second")+len("third")+len("fourth")) {
+		t.Fatalf("expected size of root header to be length of all files, got: %v", root.Size)
+	}
+
+	for hash, header := range feed.ObjectHeaders {
+		var objectLength uint64
+		if len(header.ObjectHash) > 0 {
+			object, ok := feed.Objects[header.ObjectHash]
+			if !ok {
+				t.Fatalf("object of header: %v is missing", hash)
+			}
+			objectLength = uint64(len(object.Data))
+		}
+		refs := make([]model.ObjectHeader, 0, len(header.ExternalReferences))
+		for _, ref := range header.ExternalReferences {
+			refs = append(refs, feed.ObjectHeaders[ref])
+		}
+
+		expected := ComputeSizes(objectLength, refs)
+		sizes := Sizes{
+			ObjectSize:              header.ObjectSize,
+			ExternalReferencesSize:  header.ExternalReferencesSize,
+			RecursiveSizeFirstLevel: header.RecursiveSizeFirstLevel,
+			RecursiveSizeTotal:      header.RecursiveSizeTotal,
+			Size:                    header.Size,
+		}
+		if sizes != expected {
+			t.Fatalf("expected sizes of header: %v to be: %+v, got: %+v", hash, expected, sizes)
+		}
+		if err := CheckHeaderSizes(header); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// buildTestFeed - feed built from nested directory with files, without root hash. Hash of its root header is
+// returned as well.
+func buildTestFeed(t *testing.T) (Feed, string) {
+	t.Helper()
+	root := Entry{
+		Meta: []model.Meta{{Key: "name", Value: "root"}},
+		Children: []Entry{
+			{Meta: []model.Meta{{Key: "name", Value: "nested"}}, Children: []Entry{
+				{Meta: []model.Meta{{Key: "name", Value: "first"}}, Data: []byte("first")},
+				{Meta: []model.Meta{{Key: "name", Value: "second"}}, Data: []byte("second")},
+			}},
+			{Meta: []model.Meta{{Key: "name", Value: "third"}}, Data: []byte("third")},
+			{Meta: []model.Meta{{Key: "name", Value: "fourth"}}, Data: []byte("fourth")},
+		},
+	}
+	parcel, hash, err := Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := NewFeed(model.PublishDataRequest{RootHash: model.RootHash{ObjectHeaderHash: hash}, Parcel: parcel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := feed.ObjectHeaders[hash]; !ok {
+		t.Fatalf("returned hash: %v doesn't address any built header", hash)
+	}
+	if rootHash, err := util.SHA256(parcel.ObjectHeaders[0]); err != nil || rootHash != hash {
+		t.Fatalf("expected root header to be the first one in parcel, got hash: %v, error: %v", rootHash, err)
+	}
+	return feed, hash
+}
@@ -0,0 +1,210 @@
+package parcel
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/SkycoinProject/cxo-2/pkg/archive"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/signature"
+	"github.com/SkycoinProject/cxo-2/pkg/util"
+)
+
+// ReadArchive - load feed exported to archive. Entries that don't match hashes they are stored under are kept,
+// so the rest of the feed can still be verified, and reported as problems.
+func ReadArchive(r io.Reader) (Feed, []error, error) {
+	feed := Feed{
+		ObjectHeaders: make(map[string]model.ObjectHeader),
+		Objects:       make(map[string]model.Object),
+	}
+	var problems []error
+
+	reader := archive.NewReader(r)
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Feed{}, nil, err
+		}
+
+		switch entry.Type {
+		case archive.RootHashEntry:
+			feed.RootHash = entry.RootHash
+		case archive.ObjectHeaderEntry:
+			if err := CheckHash(entry.ObjectHeader, entry.Hash); err != nil {
+				problems = append(problems, fmt.Errorf("object header: %v is corrupted: %v", entry.Hash, err))
+			}
+			feed.ObjectHeaders[entry.Hash] = entry.ObjectHeader
+		case archive.ObjectEntry:
+			if err := CheckHash(entry.Object, entry.Hash); err != nil {
+				problems = append(problems, fmt.Errorf("object: %v is corrupted: %v", entry.Hash, err))
+			}
+			feed.Objects[entry.Hash] = entry.Object
+		}
+	}
+	return feed, problems, nil
+}
+
+// verifier - state of walking feed from its root hash
+type verifier struct {
+	feed              Feed
+	allowLegacy       bool
+	problems          []error
+	visited           map[string]bool
+	visiting          map[string]struct{}
+	referencedObjects map[string]struct{}
+}
+
+// Verify - check that feed is complete, that size fields of every header match its object and referenced headers,
+// that nothing is left unreferenced and that root hash signature is valid. Legacy signature is valid only if it's
+// allowed, since it doesn't cover sequence and timestamp. Every inconsistency found is returned.
+func Verify(feed Feed, allowLegacy bool) []error {
+	v := &verifier{
+		feed:              feed,
+		allowLegacy:       allowLegacy,
+		visited:           make(map[string]bool),
+		visiting:          make(map[string]struct{}),
+		referencedObjects: make(map[string]struct{}),
+	}
+
+	if feed.RootHash.ObjectHeaderHash == "" {
+		v.problems = append(v.problems, fmt.Errorf("root hash doesn't reference object header"))
+	} else {
+		v.verifyHeader(feed.RootHash.ObjectHeaderHash, "root hash")
+	}
+
+	v.verifyReferenced()
+	v.verifySignature()
+	return v.problems
+}
+
+// verifyHeader - check header and everything it references, returns whether all referenced headers are present
+// so that recursive sizes could be checked
+func (v *verifier) verifyHeader(hash, referencedBy string) bool {
+	// header being visited is already marked as visited, so cycle has to be detected first
+	if _, ok := v.visiting[hash]; ok {
+		v.problems = append(v.problems, fmt.Errorf("object header: %v references itself", hash))
+		return false
+	}
+	if complete, ok := v.visited[hash]; ok {
+		return complete
+	}
+
+	header, ok := v.feed.ObjectHeaders[hash]
+	if !ok {
+		v.problems = append(v.problems, fmt.Errorf("object header: %v referenced by %v is missing", hash, referencedBy))
+		return false
+	}
+	v.visiting[hash] = struct{}{}
+	defer delete(v.visiting, hash)
+	// problems of header referenced from more places are reported only once
+	v.visited[hash] = false
+
+	v.verifyObject(hash, header)
+	if err := CheckHeaderSizes(header); err != nil {
+		v.problems = append(v.problems, fmt.Errorf("object header: %v has invalid size fields: %v", hash, err))
+	}
+
+	refs := make([]model.ObjectHeader, 0, len(header.ExternalReferences))
+	complete := true
+	for _, ref := range header.ExternalReferences {
+		if !v.verifyHeader(ref, fmt.Sprint("object header: ", hash)) {
+			complete = false
+			continue
+		}
+		refs = append(refs, v.feed.ObjectHeaders[ref])
+	}
+	if !complete {
+		return false
+	}
+
+	for _, mismatch := range recursiveSizeMismatches(header, ComputeSizes(header.ObjectSize, refs)) {
+		v.problems = append(v.problems, fmt.Errorf("object header: %v has invalid size fields: %v", hash, mismatch))
+	}
+	v.visited[hash] = true
+	return true
+}
+
+func (v *verifier) verifyObject(headerHash string, header model.ObjectHeader) {
+	if len(header.ObjectHash) == 0 {
+		return
+	}
+
+	v.referencedObjects[header.ObjectHash] = struct{}{}
+	object, ok := v.feed.Objects[header.ObjectHash]
+	if !ok {
+		v.problems = append(v.problems, fmt.Errorf("object: %v referenced by object header: %v is missing", header.ObjectHash, headerHash))
+		return
+	}
+	if err := CheckObjectSize(header, object); err != nil {
+		v.problems = append(v.problems, fmt.Errorf("object header: %v doesn't match its object: %v", headerHash, err))
+	}
+}
+
+// verifyReferenced - report headers and objects that are not reachable from root hash
+func (v *verifier) verifyReferenced() {
+	var unreferenced []string
+	for hash := range v.feed.ObjectHeaders {
+		if _, ok := v.visited[hash]; !ok {
+			unreferenced = append(unreferenced, fmt.Sprint("object header: ", hash))
+		}
+	}
+	for hash := range v.feed.Objects {
+		if _, ok := v.referencedObjects[hash]; !ok {
+			unreferenced = append(unreferenced, fmt.Sprint("object: ", hash))
+		}
+	}
+
+	sort.Strings(unreferenced)
+	for _, u := range unreferenced {
+		v.problems = append(v.problems, fmt.Errorf("%v is not referenced from root hash", u))
+	}
+}
+
+func (v *verifier) verifySignature() {
+	rootHash := v.feed.RootHash
+	if rootHash.Signature == "" {
+		v.problems = append(v.problems, fmt.Errorf("root hash is not signed"))
+		return
+	}
+
+	if err := signature.CheckVersion(rootHash, v.allowLegacy); err != nil {
+		v.problems = append(v.problems, fmt.Errorf("root hash signature is not valid: %v", err))
+		return
+	}
+
+	var err error
+	switch rootHash.SignatureVersion {
+	case signature.RootHashVersion:
+		err = signature.VerifyRootHash(rootHash)
+	case signature.ParcelVersion:
+		// signature covers parcel exactly as it was published, so it's recreated only if it's not known
+		parcel := v.feed.parcel
+		if parcel == nil {
+			var recreated model.Parcel
+			recreated, err = Recreate(v.feed, rootHash.ObjectHeaderHash)
+			parcel = &recreated
+		}
+		if err == nil {
+			err = signature.VerifyParcel(rootHash, *parcel)
+		}
+	}
+	if err != nil {
+		v.problems = append(v.problems, fmt.Errorf("root hash signature is not valid: %v", err))
+	}
+}
+
+// CheckHash - make sure that object header or object matches the hash it's addressed by
+func CheckHash(object interface{}, expectedHash string) error {
+	hash, err := util.SHA256(object)
+	if err != nil {
+		return fmt.Errorf("hashing failed due to error: %v", err)
+	}
+	if hash != expectedHash {
+		return fmt.Errorf("expected hash: %v but got: %v", expectedHash, hash)
+	}
+	return nil
+}
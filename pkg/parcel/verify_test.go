@@ -0,0 +1,229 @@
+package parcel
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/archive"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/signature"
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// signedTestFeed - feed built by buildTestFeed, with root hash signed by newly generated publisher using signature
+// of given version
+func signedTestFeed(t *testing.T, version uint8) (Feed, string) {
+	t.Helper()
+	feed, hash := buildTestFeed(t)
+	pubKey, secKey := cipher.GenerateKeyPair()
+	rootHash := model.RootHash{
+		Publisher:        pubKey.Hex(),
+		SignatureVersion: version,
+		Sequence:         1,
+		Timestamp:        time.Unix(1600000000, 0),
+		ObjectHeaderHash: hash,
+	}
+
+	var err error
+	switch version {
+	case signature.RootHashVersion:
+		rootHash.Signature, err = signature.SignRootHash(rootHash, pubKey, secKey)
+	case signature.ParcelVersion:
+		var parcelBytes []byte
+		if parcelBytes, err = json.Marshal(*feed.parcel); err == nil {
+			var sig cipher.Sig
+			sig, err = cipher.SignPayload(parcelBytes, secKey)
+			rootHash.Signature = sig.Hex()
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed.RootHash = rootHash
+	return feed, hash
+}
+
+func TestVerify(t *testing.T) {
+	// leaf - hash of the header of "third" file, referenced by root header
+	leaf := func(feed Feed, root string) string { return feed.ObjectHeaders[root].ExternalReferences[1] }
+	updateHeader := func(feed Feed, hash string, update func(header *model.ObjectHeader)) {
+		header := feed.ObjectHeaders[hash]
+		update(&header)
+		feed.ObjectHeaders[hash] = header
+	}
+
+	tests := []struct {
+		name        string
+		version     uint8
+		allowLegacy bool
+		// tamper - change the signed feed, headers are kept under their original hashes
+		tamper func(feed *Feed, root string)
+		// expected - substrings of expected problems, one for each
+		expected []string
+	}{
+		{name: "signed feed", version: signature.RootHashVersion},
+		{name: "allowed legacy signature", version: signature.ParcelVersion, allowLegacy: true},
+		{name: "refused legacy signature", version: signature.ParcelVersion,
+			expected: []string{"legacy signature"}},
+		// objectSize no longer matches the object and sizes of root header no longer match the changed header
+		{name: "wrong object size", version: signature.RootHashVersion,
+			tamper: func(feed *Feed, root string) {
+				updateHeader(*feed, leaf(*feed, root), func(h *model.ObjectHeader) { h.ObjectSize++; h.Size++ })
+			},
+			expected: []string{"objectSize is", "recursiveSizeFirstLevel is", "recursiveSizeTotal is"}},
+		{name: "wrong total size", version: signature.RootHashVersion,
+			tamper: func(feed *Feed, root string) {
+				updateHeader(*feed, root, func(h *model.ObjectHeader) { h.Size++ })
+			},
+			expected: []string{"size is"}},
+		{name: "wrong number of references", version: signature.RootHashVersion,
+			tamper: func(feed *Feed, root string) {
+				updateHeader(*feed, root, func(h *model.ObjectHeader) { h.ExternalReferencesSize++ })
+			},
+			expected: []string{"externalReferencesSize is"}},
+		{name: "object of other length", version: signature.RootHashVersion,
+			tamper: func(feed *Feed, root string) {
+				hash := feed.ObjectHeaders[leaf(*feed, root)].ObjectHash
+				feed.Objects[hash] = model.Object{Length: 4, Data: []byte("data")}
+			},
+			expected: []string{"objectSize is"}},
+		{name: "missing object", version: signature.RootHashVersion,
+			tamper: func(feed *Feed, root string) {
+				delete(feed.Objects, feed.ObjectHeaders[leaf(*feed, root)].ObjectHash)
+			},
+			expected: []string{"is missing"}},
+		{name: "missing header", version: signature.RootHashVersion,
+			tamper: func(feed *Feed, root string) {
+				delete(feed.ObjectHeaders, leaf(*feed, root))
+			},
+			// object of the missing header is left unreferenced
+			expected: []string{"is missing", "is not referenced"}},
+		{name: "unreferenced header and object", version: signature.RootHashVersion,
+			tamper: func(feed *Feed, root string) {
+				feed.ObjectHeaders["unreferenced"] = model.ObjectHeader{}
+				feed.Objects["unreferenced"] = model.Object{}
+			},
+			expected: []string{"object header: unreferenced is not referenced", "object: unreferenced is not referenced"}},
+		{name: "header referencing itself", version: signature.RootHashVersion,
+			tamper: func(feed *Feed, root string) {
+				updateHeader(*feed, leaf(*feed, root), func(h *model.ObjectHeader) {
+					h.ExternalReferences = []string{leaf(*feed, root)}
+					h.ExternalReferencesSize = 1
+				})
+			},
+			expected: []string{"references itself"}},
+		{name: "root hash of other header", version: signature.RootHashVersion,
+			tamper: func(feed *Feed, root string) { feed.RootHash.ObjectHeaderHash = leaf(*feed, root) },
+			// headers that are not reachable from the other header are reported as well
+			expected: []string{"signature is not valid", "is not referenced", "is not referenced",
+				"is not referenced", "is not referenced", "is not referenced", "is not referenced",
+				"is not referenced", "is not referenced"}},
+		{name: "tampered sequence", version: signature.RootHashVersion,
+			tamper:   func(feed *Feed, root string) { feed.RootHash.Sequence++ },
+			expected: []string{"signature is not valid"}},
+		{name: "unsigned root hash", version: signature.RootHashVersion,
+			tamper:   func(feed *Feed, root string) { feed.RootHash.Signature = "" },
+			expected: []string{"not signed"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			feed, root := signedTestFeed(t, tc.version)
+			if tc.tamper != nil {
+				tc.tamper(&feed, root)
+			}
+			checkProblems(t, Verify(feed, tc.allowLegacy), tc.expected)
+		})
+	}
+}
+
+func TestReadArchive(t *testing.T) {
+	feed, root := signedTestFeed(t, signature.RootHashVersion)
+	objectHash := feed.ObjectHeaders[feed.ObjectHeaders[root].ExternalReferences[1]].ObjectHash
+
+	tests := []struct {
+		name string
+		// tamper - change header or object before it's written to archive under its original hash
+		tamperHeader func(hash string, header *model.ObjectHeader)
+		tamperObject func(hash string, object *model.Object)
+		// expected - substrings of problems expected by reading and verifying the archive
+		expected []string
+	}{
+		{name: "exported feed"},
+		{name: "header with mismatched hash",
+			tamperHeader: func(hash string, header *model.ObjectHeader) {
+				if hash == root {
+					header.Meta = []model.Meta{{Key: "name", Value: "renamed"}}
+				}
+			},
+			expected: []string{"object header: " + root + " is corrupted"}},
+		// length is part of object hash too, so changed data is reported even with the same size
+		{name: "object with mismatched hash",
+			tamperObject: func(hash string, object *model.Object) {
+				if hash == objectHash {
+					object.Data = []byte("THIRD")
+				}
+			},
+			expected: []string{"object: " + objectHash + " is corrupted"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := archive.NewWriter(&buf)
+			if err := writer.WriteRootHash(feed.RootHash); err != nil {
+				t.Fatal(err)
+			}
+			for hash, header := range feed.ObjectHeaders {
+				if tc.tamperHeader != nil {
+					tc.tamperHeader(hash, &header)
+				}
+				if err := writer.WriteObjectHeader(hash, header); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for hash, object := range feed.Objects {
+				if tc.tamperObject != nil {
+					tc.tamperObject(hash, &object)
+				}
+				if err := writer.WriteObject(hash, object); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			read, problems, err := ReadArchive(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if read.RootHash.Key() != feed.RootHash.Key() || read.RootHash.Signature != feed.RootHash.Signature {
+				t.Fatalf("expected root hash: %+v, got: %+v", feed.RootHash, read.RootHash)
+			}
+			checkProblems(t, append(problems, Verify(read, false)...), tc.expected)
+		})
+	}
+}
+
+// checkProblems - fail unless every problem contains its own expected substring
+func checkProblems(t *testing.T, problems []error, expected []string) {
+	t.Helper()
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %v problems, got: %v", len(expected), problems)
+	}
+	matched := make([]bool, len(problems))
+	for _, substring := range expected {
+		found := false
+		for i, problem := range problems {
+			if !matched[i] && strings.Contains(problem.Error(), substring) {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("expected problem containing: %q, got: %v", substring, problems)
+		}
+	}
+}
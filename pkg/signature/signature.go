@@ -0,0 +1,116 @@
+package signature
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/SkycoinProject/cxo-2/pkg/errors"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	dmsgcipher "github.com/SkycoinProject/dmsg/cipher"
+)
+
+const (
+	// ParcelVersion - signature of the whole marshalled parcel. Sequence, timestamp and object header hash of the
+	// root hash are not covered by it, so it's only verified for feeds published before RootHashVersion
+	ParcelVersion uint8 = 0
+	// RootHashVersion - signature of publisher, sequence, timestamp and object header hash of the root hash.
+	// Parcel content is covered through object header hash, since every header and object is addressed by its hash
+	RootHashVersion uint8 = 1
+)
+
+// rootHashPayload - part of the root hash covered by RootHashVersion signature
+type rootHashPayload struct {
+	Version          uint8  `json:"version"`
+	Publisher        string `json:"publisher"`
+	Sequence         uint64 `json:"sequence"`
+	Timestamp        int64  `json:"timestamp"`
+	ObjectHeaderHash string `json:"objectHeaderHash"`
+}
+
+// RootHashPayload - bytes of the root hash that are signed by the publisher
+func RootHashPayload(rootHash model.RootHash) ([]byte, error) {
+	return json.Marshal(rootHashPayload{
+		Version:          RootHashVersion,
+		Publisher:        rootHash.Publisher,
+		Sequence:         rootHash.Sequence,
+		Timestamp:        rootHash.Timestamp.UnixNano(),
+		ObjectHeaderHash: rootHash.ObjectHeaderHash,
+	})
+}
+
+// SignRootHash - create RootHashVersion signature of the root hash and check it against publisher's public key
+func SignRootHash(rootHash model.RootHash, pubKey dmsgcipher.PubKey, secKey dmsgcipher.SecKey) (string, error) {
+	payload, err := RootHashPayload(rootHash)
+	if err != nil {
+		return "", fmt.Errorf("marshal root hash failed due to err: %v", err)
+	}
+
+	sig, err := dmsgcipher.SignPayload(payload, secKey)
+	if err != nil {
+		return "", fmt.Errorf("signing root hash failed due to err: %v", err)
+	}
+
+	if err = dmsgcipher.VerifyPubKeySignedPayload(pubKey, sig, payload); err != nil {
+		return "", fmt.Errorf("root hash signature verification failed due to error: %v", err)
+	}
+
+	return sig.Hex(), nil
+}
+
+// CheckVersion - make sure that signature version is known. ParcelVersion is refused unless legacy signatures are
+// allowed, since its sequence and timestamp can be changed without invalidating the signature.
+func CheckVersion(rootHash model.RootHash, allowLegacy bool) error {
+	switch rootHash.SignatureVersion {
+	case RootHashVersion:
+		return nil
+	case ParcelVersion:
+		if allowLegacy {
+			return nil
+		}
+		return errors.ErrLegacySignature
+	default:
+		return fmt.Errorf("unsupported signature version: %v", rootHash.SignatureVersion)
+	}
+}
+
+// VerifyRootHash - verify RootHashVersion signature of the root hash against its publisher
+func VerifyRootHash(rootHash model.RootHash) error {
+	if rootHash.SignatureVersion != RootHashVersion {
+		return fmt.Errorf("unexpected signature version: %v", rootHash.SignatureVersion)
+	}
+
+	payload, err := RootHashPayload(rootHash)
+	if err != nil {
+		return fmt.Errorf("marshal root hash failed due to err: %v", err)
+	}
+
+	return verify(rootHash, payload)
+}
+
+// VerifyParcel - verify ParcelVersion signature of the root hash against its publisher
+func VerifyParcel(rootHash model.RootHash, parcel model.Parcel) error {
+	if rootHash.SignatureVersion != ParcelVersion {
+		return fmt.Errorf("unexpected signature version: %v", rootHash.SignatureVersion)
+	}
+
+	parcelBytes, err := json.Marshal(parcel)
+	if err != nil {
+		return fmt.Errorf("marshal parcel failed due to err: %v", err)
+	}
+
+	return verify(rootHash, parcelBytes)
+}
+
+func verify(rootHash model.RootHash, payload []byte) error {
+	sig := dmsgcipher.Sig{}
+	if err := sig.UnmarshalText([]byte(rootHash.Signature)); err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+
+	pubKey := dmsgcipher.PubKey{}
+	if err := pubKey.UnmarshalText([]byte(rootHash.Publisher)); err != nil {
+		return fmt.Errorf("invalid publisher: %v", err)
+	}
+
+	return dmsgcipher.VerifyPubKeySignedPayload(pubKey, sig, payload)
+}
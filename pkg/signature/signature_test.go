@@ -0,0 +1,120 @@
+package signature
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+func TestVerify(t *testing.T) {
+	pubKey, secKey := cipher.GenerateKeyPair()
+	otherPubKey, _ := cipher.GenerateKeyPair()
+	parcel := model.Parcel{
+		ObjectHeaders: []model.ObjectHeader{{ObjectHash: "objectHash", ObjectSize: 4, Size: 4}},
+		Objects:       []model.Object{{Data: []byte("data")}},
+	}
+
+	signedRootHash := func(t *testing.T) model.RootHash {
+		rootHash := model.RootHash{
+			Publisher:        pubKey.Hex(),
+			SignatureVersion: RootHashVersion,
+			Sequence:         3,
+			Timestamp:        time.Unix(1600000000, 0),
+			ObjectHeaderHash: "objectHeaderHash",
+		}
+		sig, err := SignRootHash(rootHash, pubKey, secKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rootHash.Signature = sig
+		return rootHash
+	}
+	signedParcel := func(t *testing.T) model.RootHash {
+		parcelBytes, err := json.Marshal(parcel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := cipher.SignPayload(parcelBytes, secKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return model.RootHash{
+			Publisher:        pubKey.Hex(),
+			Signature:        sig.Hex(),
+			SignatureVersion: ParcelVersion,
+			Sequence:         3,
+			Timestamp:        time.Unix(1600000000, 0),
+			ObjectHeaderHash: "objectHeaderHash",
+		}
+	}
+	verify := func(allowLegacy bool) func(model.RootHash) error {
+		return func(rootHash model.RootHash) error {
+			if err := CheckVersion(rootHash, allowLegacy); err != nil {
+				return err
+			}
+			if rootHash.SignatureVersion == ParcelVersion {
+				return VerifyParcel(rootHash, parcel)
+			}
+			return VerifyRootHash(rootHash)
+		}
+	}
+	verifyRootHash := func(rootHash model.RootHash) error { return VerifyRootHash(rootHash) }
+	verifyParcel := func(rootHash model.RootHash) error { return VerifyParcel(rootHash, parcel) }
+
+	tests := []struct {
+		name     string
+		rootHash func(t *testing.T) model.RootHash
+		tamper   func(rootHash *model.RootHash)
+		verify   func(rootHash model.RootHash) error
+		valid    bool
+	}{
+		{name: "root hash signature", rootHash: signedRootHash, verify: verifyRootHash, valid: true},
+		{name: "tampered publisher", rootHash: signedRootHash, verify: verifyRootHash,
+			tamper: func(r *model.RootHash) { r.Publisher = otherPubKey.Hex() }},
+		{name: "tampered sequence", rootHash: signedRootHash, verify: verifyRootHash,
+			tamper: func(r *model.RootHash) { r.Sequence++ }},
+		{name: "tampered timestamp", rootHash: signedRootHash, verify: verifyRootHash,
+			tamper: func(r *model.RootHash) { r.Timestamp = r.Timestamp.Add(time.Nanosecond) }},
+		{name: "tampered object header hash", rootHash: signedRootHash, verify: verifyRootHash,
+			tamper: func(r *model.RootHash) { r.ObjectHeaderHash = "otherObjectHeaderHash" }},
+		{name: "root hash signature of unknown version", rootHash: signedRootHash, verify: verifyRootHash,
+			tamper: func(r *model.RootHash) { r.SignatureVersion = RootHashVersion + 1 }},
+		{name: "root hash signature verified as parcel signature", rootHash: signedRootHash, verify: verifyParcel},
+		{name: "parcel signature", rootHash: signedParcel, verify: verifyParcel, valid: true},
+		{name: "allowed legacy parcel signature", rootHash: signedParcel, verify: verify(true), valid: true},
+		// sequence isn't covered by parcel signature, so it could be replayed with higher one unless refused
+		{name: "parcel signature with changed sequence", rootHash: signedParcel, verify: verify(false),
+			tamper: func(r *model.RootHash) { r.Sequence++ }},
+		{name: "parcel signature with changed timestamp", rootHash: signedParcel, verify: verify(false),
+			tamper: func(r *model.RootHash) { r.Timestamp = r.Timestamp.Add(time.Hour) }},
+		{name: "root hash signature when legacy signatures are refused", rootHash: signedRootHash,
+			verify: verify(false), valid: true},
+		{name: "unknown version when legacy signatures are allowed", rootHash: signedRootHash, verify: verify(true),
+			tamper: func(r *model.RootHash) { r.SignatureVersion = RootHashVersion + 1 }},
+		{name: "parcel signature of other publisher", rootHash: signedParcel, verify: verifyParcel,
+			tamper: func(r *model.RootHash) { r.Publisher = otherPubKey.Hex() }},
+		{name: "parcel signature of unknown version", rootHash: signedParcel, verify: verifyParcel,
+			tamper: func(r *model.RootHash) { r.SignatureVersion = RootHashVersion + 1 }},
+		{name: "parcel signature verified as root hash signature", rootHash: signedParcel, verify: verifyRootHash},
+		{name: "malformed signature", rootHash: signedRootHash, verify: verifyRootHash,
+			tamper: func(r *model.RootHash) { r.Signature = "signature" }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rootHash := tc.rootHash(t)
+			if tc.tamper != nil {
+				tc.tamper(&rootHash)
+			}
+			err := tc.verify(rootHash)
+			if tc.valid && err != nil {
+				t.Fatalf("expected valid signature, verification failed due to error: %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Fatal("expected verification to fail")
+			}
+		})
+	}
+}
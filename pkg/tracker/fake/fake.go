@@ -0,0 +1,253 @@
+// Package fake - in-memory tracker for tests and local development. It serves the same routes as CXO Tracker
+// service, so node and CLI can be run against it on one machine without external infrastructure.
+package fake
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/parcel"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	subscribeRoute    = "/subscribe"
+	nextSequenceRoute = "/next-sequence"
+	publishDataRoute  = "/data"
+	objectRoute       = "/data/object"
+	objectHeaderRoute = "/data/object/header"
+	peersRoute        = "/peers"
+	notifyRoute       = "/notify"
+)
+
+// Tracker - feeds published to the tracker and nodes subscribed to them, held in memory
+type Tracker struct {
+	mux           sync.Mutex
+	client        *http.Client
+	handler       *http.ServeMux
+	notifications sync.WaitGroup
+	subscribers   map[string][]string
+	rootHashes    map[string]model.RootHash
+	objectHeaders map[string]model.ObjectHeader
+	objects       map[string]model.Object
+}
+
+// New - create empty tracker that notifies subscribers using given client, which has to support transport of
+// their addresses
+func New(client *http.Client) *Tracker {
+	t := &Tracker{
+		client:        client,
+		handler:       http.NewServeMux(),
+		subscribers:   make(map[string][]string),
+		rootHashes:    make(map[string]model.RootHash),
+		objectHeaders: make(map[string]model.ObjectHeader),
+		objects:       make(map[string]model.Object),
+	}
+	t.handler.HandleFunc(subscribeRoute, t.subscribeHandler)
+	t.handler.HandleFunc(nextSequenceRoute, t.nextSequenceHandler)
+	t.handler.HandleFunc(publishDataRoute, t.publishDataHandler)
+	t.handler.HandleFunc(objectRoute, t.objectHandler)
+	t.handler.HandleFunc(objectHeaderRoute, t.objectHeadersHandler)
+	t.handler.HandleFunc(peersRoute, t.peersHandler)
+	return t
+}
+
+// ServeHTTP - serve tracker routes
+func (t *Tracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.handler.ServeHTTP(w, r)
+}
+
+// RootHash - latest root hash published by the publisher
+func (t *Tracker) RootHash(publisher string) (model.RootHash, bool) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	rootHash, ok := t.rootHashes[publisher]
+	return rootHash, ok
+}
+
+// Subscribers - notify addresses of nodes subscribed to the publisher
+func (t *Tracker) Subscribers(publisher string) []string {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return append([]string(nil), t.subscribers[publisher]...)
+}
+
+// Wait - wait until subscribers are notified about everything published so far
+func (t *Tracker) Wait() {
+	t.notifications.Wait()
+}
+
+// subscribeHandler - subscribe node with address from Address header, e.g. <public key>:<port>/notify, to the
+// publisher. Node is notified right away about the latest root hash, if there is one.
+func (t *Tracker) subscribeHandler(w http.ResponseWriter, r *http.Request) {
+	publisher := r.URL.Query().Get("pubKey")
+	address := r.Header.Get("Address")
+	if publisher == "" || address == "" {
+		http.Error(w, "public key and address are required", http.StatusBadRequest)
+		return
+	}
+
+	t.mux.Lock()
+	subscribed := false
+	for _, subscriber := range t.subscribers[publisher] {
+		subscribed = subscribed || subscriber == address
+	}
+	if !subscribed {
+		t.subscribers[publisher] = append(t.subscribers[publisher], address)
+	}
+	rootHash, published := t.rootHashes[publisher]
+	t.mux.Unlock()
+
+	if published {
+		t.notify(rootHash, address)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// nextSequenceHandler - sequence following the latest published one as 8 bytes big endian, 404 if publisher
+// didn't publish anything yet
+func (t *Tracker) nextSequenceHandler(w http.ResponseWriter, r *http.Request) {
+	t.mux.Lock()
+	rootHash, ok := t.rootHashes[r.URL.Query().Get("pubKey")]
+	t.mux.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	body := make([]byte, 8)
+	binary.BigEndian.PutUint64(body, rootHash.Sequence+1)
+	_, _ = w.Write(body)
+}
+
+// publishDataHandler - store complete and signed feed of newer sequence than the latest one and notify subscribers
+func (t *Tracker) publishDataHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req model.PublishDataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	feed, err := parcel.NewFeed(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if problems := parcel.Verify(feed, false); len(problems) > 0 {
+		http.Error(w, fmt.Sprint("published data is not valid: ", problems), http.StatusUnprocessableEntity)
+		return
+	}
+
+	t.mux.Lock()
+	latest, ok := t.rootHashes[req.RootHash.Publisher]
+	if ok && latest.Sequence >= req.RootHash.Sequence {
+		t.mux.Unlock()
+		http.Error(w, fmt.Sprintf("sequence: %v is already published", req.RootHash.Sequence), http.StatusConflict)
+		return
+	}
+	for hash, header := range feed.ObjectHeaders {
+		t.objectHeaders[hash] = header
+	}
+	for hash, object := range feed.Objects {
+		t.objects[hash] = object
+	}
+	t.rootHashes[req.RootHash.Publisher] = req.RootHash
+	subscribers := append([]string(nil), t.subscribers[req.RootHash.Publisher]...)
+	t.mux.Unlock()
+
+	log.Infof("Publisher: %v published sequence: %v", req.RootHash.Publisher, req.RootHash.Sequence)
+	for _, subscriber := range subscribers {
+		t.notify(req.RootHash, subscriber)
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// objectHeadersHandler - object headers with given hashes, 404 if any of them is missing
+func (t *Tracker) objectHeadersHandler(w http.ResponseWriter, r *http.Request) {
+	hashes := r.URL.Query()["hash"]
+	if len(hashes) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	t.mux.Lock()
+	resp := model.GetObjectHeadersResponse{ObjectHeaders: make([]model.ObjectHeader, 0, len(hashes))}
+	for _, hash := range hashes {
+		header, ok := t.objectHeaders[hash]
+		if !ok {
+			t.mux.Unlock()
+			http.NotFound(w, r)
+			return
+		}
+		resp.ObjectHeaders = append(resp.ObjectHeaders, header)
+	}
+	t.mux.Unlock()
+	writeJSON(w, resp)
+}
+
+// objectHandler - object with given hash
+func (t *Tracker) objectHandler(w http.ResponseWriter, r *http.Request) {
+	t.mux.Lock()
+	object, ok := t.objects[r.URL.Query().Get("hash")]
+	t.mux.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, object)
+}
+
+// peersHandler - addresses of nodes subscribed to the publisher, so they can exchange data with each other
+func (t *Tracker) peersHandler(w http.ResponseWriter, r *http.Request) {
+	subscribers := t.Subscribers(r.URL.Query().Get("pubKey"))
+	resp := model.GetPeersResponse{Peers: make([]string, 0, len(subscribers))}
+	for _, subscriber := range subscribers {
+		resp.Peers = append(resp.Peers, strings.TrimSuffix(subscriber, notifyRoute))
+	}
+	writeJSON(w, resp)
+}
+
+// notify - send root hash to subscriber in background, so publisher doesn't wait for subscribers
+func (t *Tracker) notify(rootHash model.RootHash, address string) {
+	body, err := json.Marshal(rootHash)
+	if err != nil {
+		log.Errorf("Marshalling root hash with key: %v failed due to error: %v", rootHash.Key(), err)
+		return
+	}
+	if !strings.Contains(address, "://") {
+		address = config.DMSGTransport + "://" + address
+	}
+
+	t.notifications.Add(1)
+	go func() {
+		defer t.notifications.Done()
+		resp, err := t.client.Post(address, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Warnf("Notifying: %v about root hash with key: %v failed due to error: %v", address, rootHash.Key(), err)
+			return
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			log.Warnf("Notifying: %v about root hash with key: %v returned status: %v", address, rootHash.Key(),
+				resp.Status)
+		}
+	}()
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("Writing response failed due to error: %v", err)
+	}
+}
@@ -0,0 +1,343 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	"github.com/SkycoinProject/cxo-2/pkg/parcel"
+	"github.com/SkycoinProject/cxo-2/pkg/signature"
+	"github.com/SkycoinProject/dmsg/cipher"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	log.SetLevel(log.ErrorLevel)
+	os.Exit(m.Run())
+}
+
+// subscriber - node notified by the tracker, answering every notification with given status
+type subscriber struct {
+	mux        sync.Mutex
+	server     *httptest.Server
+	rootHashes []model.RootHash
+}
+
+func newSubscriber(status int) *subscriber {
+	s := &subscriber{}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rootHash model.RootHash
+		if r.URL.Path != notifyRoute || json.NewDecoder(r.Body).Decode(&rootHash) != nil {
+			http.Error(w, "invalid notification", http.StatusBadRequest)
+			return
+		}
+		s.mux.Lock()
+		s.rootHashes = append(s.rootHashes, rootHash)
+		s.mux.Unlock()
+		w.WriteHeader(status)
+	}))
+	return s
+}
+
+func (s *subscriber) address() string {
+	return s.server.URL + notifyRoute
+}
+
+// sequences - sorted sequences of root hashes the subscriber was notified about, notifications are sent concurrently
+func (s *subscriber) sequences() []uint64 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	sequences := make([]uint64, 0, len(s.rootHashes))
+	for _, rootHash := range s.rootHashes {
+		sequences = append(sequences, rootHash.Sequence)
+	}
+	sort.Slice(sequences, func(i, j int) bool { return sequences[i] < sequences[j] })
+	return sequences
+}
+
+// publisher - keys feeds are signed with
+type publisher struct {
+	pubKey cipher.PubKey
+	secKey cipher.SecKey
+}
+
+func newPublisher() publisher {
+	pubKey, secKey := cipher.GenerateKeyPair()
+	return publisher{pubKey: pubKey, secKey: secKey}
+}
+
+// feed - signed publish data request with file of given content
+func (p publisher) feed(t *testing.T, sequence uint64, content string) model.PublishDataRequest {
+	t.Helper()
+	feedParcel, hash, err := parcel.Build(parcel.Entry{
+		Meta: []model.Meta{{Key: "name", Value: "file.txt"}},
+		Data: []byte(content),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootHash := model.RootHash{
+		Publisher:        p.pubKey.Hex(),
+		SignatureVersion: signature.RootHashVersion,
+		Sequence:         sequence,
+		Timestamp:        time.Now(),
+		ObjectHeaderHash: hash,
+	}
+	if rootHash.Signature, err = signature.SignRootHash(rootHash, p.pubKey, p.secKey); err != nil {
+		t.Fatal(err)
+	}
+	return model.PublishDataRequest{RootHash: rootHash, Parcel: feedParcel}
+}
+
+func serve(tracker *Tracker, method, url string, body []byte, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, bytes.NewReader(body))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	tracker.ServeHTTP(rec, req)
+	return rec
+}
+
+func publish(t *testing.T, tracker *Tracker, req model.PublishDataRequest) int {
+	t.Helper()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return serve(tracker, http.MethodPost, publishDataRoute, body, nil).Code
+}
+
+func subscribe(tracker *Tracker, publisher, address string) int {
+	header := http.Header{}
+	if address != "" {
+		header.Set("Address", address)
+	}
+	return serve(tracker, http.MethodGet, subscribeRoute+"?pubKey="+publisher, nil, header).Code
+}
+
+func equalSequences(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSubscribe(t *testing.T) {
+	p := newPublisher()
+	tracker := New(http.DefaultClient)
+	early, late := newSubscriber(http.StatusOK), newSubscriber(http.StatusOK)
+	defer early.server.Close()
+	defer late.server.Close()
+
+	tests := []struct {
+		name      string
+		publisher string
+		address   string
+		status    int
+	}{
+		{name: "missing public key", address: early.address(), status: http.StatusBadRequest},
+		{name: "missing address", publisher: p.pubKey.Hex(), status: http.StatusBadRequest},
+		{name: "subscribe", publisher: p.pubKey.Hex(), address: early.address(), status: http.StatusOK},
+		// subscriber isn't notified twice for subscribing twice
+		{name: "subscribe again", publisher: p.pubKey.Hex(), address: early.address(), status: http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if status := subscribe(tracker, tc.publisher, tc.address); status != tc.status {
+				t.Fatalf("expected status: %v, got: %v", tc.status, status)
+			}
+		})
+	}
+	if subscribers := tracker.Subscribers(p.pubKey.Hex()); len(subscribers) != 1 || subscribers[0] != early.address() {
+		t.Fatalf("expected single subscriber: %v, got: %v", early.address(), subscribers)
+	}
+	tracker.Wait()
+	if sequences := early.sequences(); len(sequences) != 0 {
+		t.Fatalf("expected no notification before anything is published, got: %v", sequences)
+	}
+
+	if status := publish(t, tracker, p.feed(t, 1, "content")); status != http.StatusCreated {
+		t.Fatalf("expected publishing to succeed, got status: %v", status)
+	}
+	// late subscriber is notified about the latest root hash right away
+	if status := subscribe(tracker, p.pubKey.Hex(), late.address()); status != http.StatusOK {
+		t.Fatalf("expected subscribing to succeed, got status: %v", status)
+	}
+	tracker.Wait()
+	if sequences := early.sequences(); !equalSequences(sequences, []uint64{1}) {
+		t.Fatalf("expected subscriber to be notified about published sequence, got: %v", sequences)
+	}
+	if sequences := late.sequences(); !equalSequences(sequences, []uint64{1}) {
+		t.Fatalf("expected late subscriber to be notified on subscribing, got: %v", sequences)
+	}
+}
+
+func TestNextSequence(t *testing.T) {
+	p := newPublisher()
+	tracker := New(http.DefaultClient)
+
+	for _, published := range []uint64{0, 1, 5} {
+		if published > 0 {
+			req := p.feed(t, published, fmt.Sprint("content ", published))
+			if status := publish(t, tracker, req); status != http.StatusCreated {
+				t.Fatalf("expected publishing to succeed, got status: %v", status)
+			}
+		}
+
+		rec := serve(tracker, http.MethodGet, nextSequenceRoute+"?pubKey="+p.pubKey.Hex(), nil, nil)
+		if published == 0 {
+			if rec.Code != http.StatusNotFound {
+				t.Fatalf("expected status: %v before publishing, got: %v", http.StatusNotFound, rec.Code)
+			}
+			continue
+		}
+		if rec.Code != http.StatusOK || rec.Body.Len() != 8 {
+			t.Fatalf("expected 8 bytes of sequence, got status: %v, body: %v", rec.Code, rec.Body.Bytes())
+		}
+		if next := binary.BigEndian.Uint64(rec.Body.Bytes()); next != published+1 {
+			t.Fatalf("expected next sequence: %v, got: %v", published+1, next)
+		}
+	}
+}
+
+func TestPublishData(t *testing.T) {
+	p := newPublisher()
+	tracker := New(http.DefaultClient)
+	notified := newSubscriber(http.StatusOK)
+	// subscriber that refuses notification doesn't make publishing fail
+	refusing := newSubscriber(http.StatusForbidden)
+	defer notified.server.Close()
+	defer refusing.server.Close()
+	subscribe(tracker, p.pubKey.Hex(), notified.address())
+	subscribe(tracker, p.pubKey.Hex(), refusing.address())
+
+	encode := func(req model.PublishDataRequest) []byte {
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+	tampered := p.feed(t, 2, "content")
+	tampered.Parcel.Objects[0].Data = []byte("CONTENT")
+
+	tests := []struct {
+		name   string
+		method string
+		body   []byte
+		status int
+		// latest - sequence expected to be the latest one after the request
+		latest uint64
+	}{
+		{name: "not a post", method: http.MethodGet, status: http.StatusNotFound},
+		{name: "malformed request", body: []byte("{"), status: http.StatusBadRequest},
+		{name: "publish", body: encode(p.feed(t, 2, "content")), status: http.StatusCreated, latest: 2},
+		{name: "tampered data", body: encode(tampered), status: http.StatusUnprocessableEntity, latest: 2},
+		{name: "same sequence", body: encode(p.feed(t, 2, "other content")), status: http.StatusConflict, latest: 2},
+		{name: "older sequence", body: encode(p.feed(t, 1, "other content")), status: http.StatusConflict, latest: 2},
+		{name: "newer sequence", body: encode(p.feed(t, 3, "new content")), status: http.StatusCreated, latest: 3},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			if rec := serve(tracker, method, publishDataRoute, tc.body, nil); rec.Code != tc.status {
+				t.Fatalf("expected status: %v, got: %v, %v", tc.status, rec.Code, rec.Body.String())
+			}
+			rootHash, ok := tracker.RootHash(p.pubKey.Hex())
+			if ok != (tc.latest > 0) || rootHash.Sequence != tc.latest {
+				t.Fatalf("expected latest sequence: %v, got: %v", tc.latest, rootHash.Sequence)
+			}
+		})
+	}
+
+	// subscribers are notified only about accepted sequences
+	tracker.Wait()
+	for _, s := range []*subscriber{notified, refusing} {
+		if sequences := s.sequences(); !equalSequences(sequences, []uint64{2, 3}) {
+			t.Fatalf("expected notifications about sequences: %v, got: %v", []uint64{2, 3}, sequences)
+		}
+	}
+}
+
+func TestData(t *testing.T) {
+	p := newPublisher()
+	tracker := New(http.DefaultClient)
+	subscribed := newSubscriber(http.StatusOK)
+	defer subscribed.server.Close()
+	req := p.feed(t, 1, "content")
+	if status := publish(t, tracker, req); status != http.StatusCreated {
+		t.Fatalf("expected publishing to succeed, got status: %v", status)
+	}
+	subscribe(tracker, p.pubKey.Hex(), subscribed.address())
+	tracker.Wait()
+
+	feed, err := parcel.NewFeed(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerHash := req.RootHash.ObjectHeaderHash
+	objectHash := feed.ObjectHeaders[headerHash].ObjectHash
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		// body - substring of expected response
+		body string
+	}{
+		{name: "object header", url: objectHeaderRoute + "?hash=" + headerHash, status: http.StatusOK,
+			body: objectHash},
+		{name: "missing object header", url: objectHeaderRoute + "?hash=" + headerHash + "&hash=missing",
+			status: http.StatusNotFound},
+		{name: "no object header hash", url: objectHeaderRoute, status: http.StatusNotFound},
+		{name: "object", url: objectRoute + "?hash=" + objectHash, status: http.StatusOK},
+		{name: "missing object", url: objectRoute + "?hash=missing", status: http.StatusNotFound},
+		// peers are reached on their base address
+		{name: "peers", url: peersRoute + "?pubKey=" + p.pubKey.Hex(), status: http.StatusOK,
+			body: `"` + subscribed.server.URL + `"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(tracker, http.MethodGet, tc.url, nil, nil)
+			if rec.Code != tc.status {
+				t.Fatalf("expected status: %v, got: %v", tc.status, rec.Code)
+			}
+			body, err := ioutil.ReadAll(rec.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(body), tc.body) {
+				t.Fatalf("expected response containing: %v, got: %s", tc.body, body)
+			}
+		})
+	}
+
+	rec := serve(tracker, http.MethodGet, objectRoute+"?hash="+objectHash, nil, nil)
+	var object model.Object
+	if err := json.Unmarshal(rec.Body.Bytes(), &object); err != nil {
+		t.Fatal(err)
+	}
+	if string(object.Data) != "content" {
+		t.Fatalf("expected object with published content, got: %q", object.Data)
+	}
+}
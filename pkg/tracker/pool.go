@@ -0,0 +1,241 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	"github.com/SkycoinProject/cxo-2/pkg/logging"
+	"github.com/SkycoinProject/cxo-2/pkg/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxFailures - number of consecutive failed requests after which tracker is considered unhealthy
+const maxFailures = 3
+
+// StatusError - response to request with other than expected status. Server errors mean the tracker is failing,
+// other statuses are answers of working tracker, such as about data it doesn't have.
+type StatusError struct {
+	Request    string
+	Status     string
+	StatusCode int
+}
+
+// NewStatusError - error of request that got response with unexpected status
+func NewStatusError(request string, resp *http.Response) *StatusError {
+	return &StatusError{Request: request, Status: resp.Status, StatusCode: resp.StatusCode}
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%v returned status: %v", e.Request, e.Status)
+}
+
+// isFailure - whether request failed because tracker is unreachable or broken, rather than because it refused
+// the request or doesn't have requested data. Only failures make tracker unhealthy.
+func isFailure(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode >= http.StatusInternalServerError
+}
+
+// Pool - trackers ordered by priority. Trackers that keep failing are tried only after healthy ones, until
+// successful request or health check finds them reachable again. Each feed sticks to the tracker that last served
+// it or notified about it, since that tracker is the one known to have its data.
+type Pool struct {
+	mux      sync.Mutex
+	trackers []*tracker
+	affinity map[string]*tracker
+	logger   log.FieldLogger
+}
+
+type tracker struct {
+	config.TrackerConfig
+	failures  int
+	checkedAt time.Time
+}
+
+func (t *tracker) healthy() bool {
+	return t.failures < maxFailures
+}
+
+// is - whether tracker has given public key, or given host if it's reached over HTTP
+func (t *tracker) is(id string) bool {
+	if !t.PubKey.Null() {
+		return t.PubKey.Hex() == id
+	}
+	u, err := url.Parse(t.Address)
+	return err == nil && u.Hostname() == id
+}
+
+// NewPool - create pool of the given trackers, which logs changes of their health to the logger
+func NewPool(trackers []config.TrackerConfig, logger log.FieldLogger) *Pool {
+	p := &Pool{affinity: make(map[string]*tracker), logger: logger}
+	for _, cfg := range trackers {
+		p.trackers = append(p.trackers, &tracker{TrackerConfig: cfg})
+	}
+	sort.SliceStable(p.trackers, func(i, j int) bool { return p.trackers[i].Priority < p.trackers[j].Priority })
+	return p
+}
+
+// Addresses - addresses of all trackers, ordered by priority
+func (p *Pool) Addresses() []string {
+	addresses := make([]string, 0, len(p.trackers))
+	for _, t := range p.trackers {
+		addresses = append(addresses, t.Address)
+	}
+	return addresses
+}
+
+// Do - send request to trackers until one of them succeeds, starting with the tracker of the feed. Error of the
+// last tracker is returned if all of them fail. Feed can be empty for requests that don't belong to any feed.
+// Only transport errors and server error statuses, wrapped by errors request returns, count as tracker failures.
+func (p *Pool) Do(feed string, request func(address string) error) error {
+	return p.do(feed, request, true)
+}
+
+// Query - send request to trackers the same way as Do, but tracker that succeeds doesn't become the tracker of the
+// feed, for requests whose success doesn't mean that tracker has data of the feed
+func (p *Pool) Query(feed string, request func(address string) error) error {
+	return p.do(feed, request, false)
+}
+
+func (p *Pool) do(feed string, request func(address string) error, prefer bool) error {
+	preferredFeed := ""
+	if prefer {
+		preferredFeed = feed
+	}
+	var err error
+	for _, t := range p.order(feed) {
+		if err = request(t.Address); err == nil {
+			p.succeeded(t, preferredFeed)
+			return nil
+		}
+		if isFailure(err) {
+			p.failed(t, err)
+			continue
+		}
+		p.logger.WithField(logging.TrackerField, t.Address).WithError(err).Debug("Tracker couldn't serve request")
+	}
+	if err == nil {
+		return fmt.Errorf("no tracker is configured")
+	}
+	return err
+}
+
+// Prefer - make tracker with given public key, or host for trackers reached over HTTP, the first one to be asked
+// for data of the feed
+func (p *Pool) Prefer(feed string, id string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	for _, t := range p.trackers {
+		if t.is(id) {
+			p.affinity[feed] = t
+			return
+		}
+	}
+}
+
+// Statuses - health of all trackers, ordered by priority
+func (p *Pool) Statuses() []model.TrackerStatus {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	statuses := make([]model.TrackerStatus, 0, len(p.trackers))
+	for _, t := range p.trackers {
+		statuses = append(statuses, model.TrackerStatus{
+			Address:   t.Address,
+			Priority:  t.Priority,
+			Healthy:   t.healthy(),
+			Failures:  t.failures,
+			CheckedAt: t.checkedAt,
+		})
+	}
+	return statuses
+}
+
+// order - tracker of the feed if it's healthy, then other healthy trackers and unhealthy ones as the last resort
+func (p *Pool) order(feed string) []*tracker {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	order := make([]*tracker, 0, len(p.trackers))
+	preferred, ok := p.affinity[feed]
+	if ok && preferred.healthy() {
+		order = append(order, preferred)
+	}
+	for _, t := range p.trackers {
+		if t.healthy() && t != preferred {
+			order = append(order, t)
+		}
+	}
+	for _, t := range p.trackers {
+		if !t.healthy() {
+			order = append(order, t)
+		}
+	}
+	return order
+}
+
+func (p *Pool) succeeded(t *tracker, feed string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if !t.healthy() {
+		p.logger.WithField(logging.TrackerField, t.Address).Info("Tracker is healthy again")
+	}
+	t.failures = 0
+	t.checkedAt = time.Now()
+	if feed != "" {
+		p.affinity[feed] = t
+	}
+}
+
+func (p *Pool) failed(t *tracker, err error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	t.failures++
+	t.checkedAt = time.Now()
+	logger := p.logger.WithField(logging.TrackerField, t.Address)
+	logger.WithError(err).Warn("Request to tracker failed")
+	if t.failures == maxFailures {
+		logger.Warnf("Tracker is unhealthy after %v failed requests", t.failures)
+	}
+}
+
+// CheckHealth - probe every tracker, so that outage is noticed before data is needed and tracker that recovered
+// is used again. Any response means tracker is reachable, since trackers don't have dedicated health route.
+func (p *Pool) CheckHealth(client *http.Client) {
+	for _, t := range p.trackers {
+		resp, err := client.Get(t.Address)
+		if err != nil {
+			p.failed(t, err)
+			continue
+		}
+		_ = resp.Body.Close()
+		p.succeeded(t, "")
+	}
+}
+
+// RunHealthChecks - check health of trackers right away and then every interval until context is done
+func (p *Pool) RunHealthChecks(ctx context.Context, client *http.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	p.CheckHealth(client)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.CheckHealth(client)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+func newTestPool(addresses ...string) *Pool {
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+	// trackers are given in reverse order of priority, so the pool has to sort them
+	trackers := make([]config.TrackerConfig, 0, len(addresses))
+	for i, address := range addresses {
+		trackers = append([]config.TrackerConfig{{Address: address, Priority: i}}, trackers...)
+	}
+	return NewPool(trackers, logger)
+}
+
+// asked - addresses request is sent to by Do, in order, with given errors returned by trackers
+func asked(p *Pool, feed string, errs map[string]error) ([]string, error) {
+	var addresses []string
+	err := p.Do(feed, func(address string) error {
+		addresses = append(addresses, address)
+		return errs[address]
+	})
+	return addresses, err
+}
+
+func equalAddresses(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPoolPriority(t *testing.T) {
+	p := newTestPool("first", "second", "third")
+	if addresses := p.Addresses(); !equalAddresses(addresses, []string{"first", "second", "third"}) {
+		t.Fatalf("expected trackers ordered by priority, got: %v", addresses)
+	}
+
+	tests := []struct {
+		name     string
+		errs     map[string]error
+		expected []string
+		failed   bool
+	}{
+		{name: "first tracker succeeds", expected: []string{"first"}},
+		{name: "failover to next tracker", errs: map[string]error{"first": errors.New("failed")},
+			expected: []string{"first", "second"}},
+		{name: "every tracker fails", failed: true, expected: []string{"first", "second", "third"},
+			errs: map[string]error{"first": errors.New("failed"), "second": errors.New("failed"),
+				"third": errors.New("failed")}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			addresses, err := asked(newTestPool("first", "second", "third"), "", tc.errs)
+			if (err != nil) != tc.failed {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !equalAddresses(addresses, tc.expected) {
+				t.Fatalf("expected trackers: %v to be asked, got: %v", tc.expected, addresses)
+			}
+		})
+	}
+}
+
+func TestPoolFailures(t *testing.T) {
+	transportErr := &url.Error{Op: "Get", URL: "http://first", Err: errors.New("connection refused")}
+	statusErr := func(code int) error {
+		return &StatusError{Request: "request", Status: http.StatusText(code), StatusCode: code}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		// unhealthy - whether tracker failing with the error repeatedly is tried only after other trackers
+		unhealthy bool
+	}{
+		{name: "transport error", err: transportErr, unhealthy: true},
+		{name: "wrapped transport error", err: fmt.Errorf("fetch failed due to error: %w", transportErr), unhealthy: true},
+		{name: "server error", err: statusErr(http.StatusInternalServerError), unhealthy: true},
+		{name: "wrapped server error", err: fmt.Errorf("fetch failed due to error: %w", statusErr(http.StatusBadGateway)),
+			unhealthy: true},
+		// tracker is working, it just doesn't have the data
+		{name: "data not found", err: statusErr(http.StatusNotFound)},
+		{name: "wrapped data not found", err: fmt.Errorf("fetch failed due to error: %w", statusErr(http.StatusNotFound))},
+		{name: "invalid data", err: errors.New("received invalid object header")},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newTestPool("first", "second")
+			for i := 0; i < maxFailures; i++ {
+				if _, err := asked(p, "", map[string]error{"first": tc.err}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			statuses := p.Statuses()
+			if statuses[0].Healthy == tc.unhealthy {
+				t.Fatalf("expected tracker to be healthy: %v, got status: %+v", !tc.unhealthy, statuses[0])
+			}
+			expected := []string{"first"}
+			if tc.unhealthy {
+				expected = []string{"second"}
+			}
+			if addresses, _ := asked(p, "", nil); !equalAddresses(addresses, expected) {
+				t.Fatalf("expected trackers: %v to be asked, got: %v", expected, addresses)
+			}
+		})
+	}
+}
+
+func TestPoolAffinity(t *testing.T) {
+	// trackers reached over HTTP are preferred by host
+	const first, second = "http://first", "http://second"
+	p := newTestPool(first, second)
+	failFirst := map[string]error{first: errors.New("failed")}
+
+	// tracker that answered query about the feed doesn't become its tracker
+	if err := p.Query("feed", func(address string) error { return failFirst[address] }); err != nil {
+		t.Fatal(err)
+	}
+	if addresses, _ := asked(p, "feed", failFirst); !equalAddresses(addresses, []string{first, second}) {
+		t.Fatalf("expected trackers to be asked by priority after query, got: %v", addresses)
+	}
+
+	// tracker that served the feed is asked first for the feed, other feeds still go by priority
+	if addresses, _ := asked(p, "feed", nil); !equalAddresses(addresses, []string{second}) {
+		t.Fatalf("expected tracker that served the feed to be asked first, got: %v", addresses)
+	}
+	if addresses, _ := asked(p, "other", nil); !equalAddresses(addresses, []string{first}) {
+		t.Fatalf("expected trackers of other feed to be asked by priority, got: %v", addresses)
+	}
+
+	p.Prefer("feed", "first")
+	if addresses, _ := asked(p, "feed", nil); !equalAddresses(addresses, []string{first}) {
+		t.Fatalf("expected preferred tracker to be asked first, got: %v", addresses)
+	}
+}
+
+func TestPoolRecovery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	p := newTestPool(server.URL, "second")
+
+	down := &url.Error{Op: "Get", URL: server.URL, Err: errors.New("connection refused")}
+	for i := 0; i < maxFailures; i++ {
+		if _, err := asked(p, "", map[string]error{server.URL: down}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if addresses, _ := asked(p, "", nil); !equalAddresses(addresses, []string{"second"}) {
+		t.Fatalf("expected failing tracker to be asked last, got: %v", addresses)
+	}
+
+	// any response to health check means tracker is reachable again
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.RunHealthChecks(ctx, server.Client(), 10*time.Millisecond)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !p.Statuses()[0].Healthy {
+		if time.Now().After(deadline) {
+			t.Fatal("tracker didn't recover after health check")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if addresses, _ := asked(p, "", nil); !equalAddresses(addresses, []string{server.URL}) {
+		t.Fatalf("expected recovered tracker to be asked first again, got: %v", addresses)
+	}
+}
@@ -0,0 +1,130 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/SkycoinProject/cxo-2/pkg/config"
+	dmsghttp "github.com/SkycoinProject/dmsg-http"
+	"github.com/SkycoinProject/dmsg/cipher"
+	"github.com/SkycoinProject/dmsg/disc"
+)
+
+// Server - serves node routes to trackers and other nodes over one of the transports. Serve returns nil once
+// server is closed.
+type Server interface {
+	Serve(handler http.Handler) error
+	Close() error
+	// Listening - whether server accepts connections
+	Listening() bool
+}
+
+// NewClient - HTTP client that sends requests over dmsg or plain HTTP depending on scheme of the URL, so trackers
+// and peers using different transports can be mixed
+func NewClient(discovery disc.APIClient, pubKey cipher.PubKey, secKey cipher.SecKey) *http.Client {
+	dmsgClient := dmsghttp.DMSGClient(discovery, pubKey, secKey)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.RegisterProtocol(config.DMSGTransport, dmsgClient.Transport)
+	return &http.Client{
+		Transport: transport,
+		Timeout:   dmsgClient.Timeout,
+	}
+}
+
+// NewServer - server of the transport selected in config
+func NewServer(cfg config.Config) Server {
+	if cfg.Server.Transport == config.HTTPTransport {
+		return &httpServer{server: &http.Server{Addr: cfg.Server.Address}}
+	}
+	return &dmsgServer{server: &dmsghttp.Server{
+		PubKey:    cfg.PubKey,
+		SecKey:    cfg.SecKey,
+		Port:      cfg.Port,
+		Discovery: cfg.Discovery,
+	}}
+}
+
+// Address - address trackers and other nodes use to reach the node, <public key>:<port> with dmsg transport and
+// http://<host>:<port> with HTTP transport
+func Address(cfg config.Config) string {
+	if cfg.Server.Transport == config.HTTPTransport {
+		return fmt.Sprintf("%v://%v", config.HTTPTransport, cfg.Server.Address)
+	}
+	return fmt.Sprintf("%v:%v", cfg.PubKey.Hex(), cfg.Port)
+}
+
+type httpServer struct {
+	server    *http.Server
+	listening int32
+}
+
+func (s *httpServer) Serve(handler http.Handler) error {
+	s.server.Handler = handler
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt32(&s.listening, 1)
+	defer atomic.StoreInt32(&s.listening, 0)
+	if err := s.server.Serve(listener); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (s *httpServer) Close() error {
+	return s.server.Close()
+}
+
+func (s *httpServer) Listening() bool {
+	return atomic.LoadInt32(&s.listening) == 1
+}
+
+// dmsgServer - dmsg server doesn't report when its listener is ready, so it's considered listening from start
+// until it stops
+type dmsgServer struct {
+	mux     sync.Mutex
+	server  *dmsghttp.Server
+	started bool
+	closed  bool
+}
+
+func (s *dmsgServer) Serve(handler http.Handler) error {
+	s.mux.Lock()
+	if s.closed {
+		s.mux.Unlock()
+		return nil
+	}
+	s.started = true
+	s.mux.Unlock()
+
+	err := s.server.Serve(handler)
+	s.mux.Lock()
+	s.started = false
+	s.mux.Unlock()
+	if err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (s *dmsgServer) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.closed = true
+	// dmsg server can be closed only once it's started
+	if !s.started {
+		return nil
+	}
+	return s.server.Close()
+}
+
+func (s *dmsgServer) Listening() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.started && !s.closed
+}
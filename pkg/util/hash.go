@@ -0,0 +1,17 @@
+package util
+
+import (
+	"encoding/json"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// SHA256 - hex encoded sha256 sum of object's json representation, used for hashing object headers and objects
+func SHA256(object interface{}) (string, error) {
+	b, err := json.Marshal(object)
+	if err != nil {
+		return "", err
+	}
+
+	return cipher.SumSHA256(b).Hex(), nil
+}